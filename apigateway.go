@@ -0,0 +1,295 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// CloudWatch metric names differ between REST (v1) and HTTP/WebSocket (v2) APIs.
+// Config uses REST names, they are translated per protocol before fetching.
+var apiGatewayMetricNames = map[string]map[string]string{
+	"REST": {
+		"Count":    "Count",
+		"4XXError": "4XXError",
+		"5XXError": "5XXError",
+		"Latency":  "Latency",
+	},
+	"HTTP": {
+		"Count":         "Count",
+		"4XXError":      "4xx",
+		"5XXError":      "5xx",
+		"Latency":       "Latency",
+		"DataProcessed": "DataProcessed",
+	},
+	"WEBSOCKET": {
+		"Count":    "MessageCount",
+		"4XXError": "ClientError",
+		"5XXError": "ExecutionError",
+		"Latency":  "IntegrationLatency",
+	},
+}
+
+type ApiGatewayStage struct {
+	ApiId         string
+	ApiName       string
+	Stage         string
+	Protocol      string
+	Tags          map[string]*string
+	Region        string
+	DimensionTags []string
+}
+
+func GetRestApiStages(session *session.Session, resource *MonitoredResource) ([]ApiGatewayStage, error) {
+	region := session.Config.Region
+	stages := make([]ApiGatewayStage, 0)
+	svc := apigateway.New(session)
+	apis := make([]*apigateway.RestApi, 0)
+
+	input := &apigateway.GetRestApisInput{
+		Limit: aws.Int64(500),
+	}
+	for {
+		output, err := svc.GetRestApis(input)
+		if err != nil {
+			return stages, err
+		}
+		apis = append(apis, output.Items...)
+		if output.Position == nil {
+			break
+		}
+		input.Position = output.Position
+	}
+
+	for _, api := range apis {
+		output, err := svc.GetStages(&apigateway.GetStagesInput{
+			RestApiId: api.Id,
+		})
+		if err != nil {
+			return stages, err
+		}
+		for _, s := range output.Item {
+			stages = append(stages, ApiGatewayStage{
+				ApiId:         *api.Id,
+				ApiName:       aws.StringValue(api.Name),
+				Stage:         *s.StageName,
+				Protocol:      "REST",
+				Tags:          api.Tags,
+				Region:        *region,
+				DimensionTags: resource.DimensionTags,
+			})
+		}
+	}
+	return stages, nil
+}
+
+func GetV2ApiStages(session *session.Session, resource *MonitoredResource) ([]ApiGatewayStage, error) {
+	region := session.Config.Region
+	stages := make([]ApiGatewayStage, 0)
+	svc := apigatewayv2.New(session)
+	apis := make([]*apigatewayv2.Api, 0)
+
+	input := &apigatewayv2.GetApisInput{}
+	for {
+		output, err := svc.GetApis(input)
+		if err != nil {
+			return stages, err
+		}
+		apis = append(apis, output.Items...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	for _, api := range apis {
+		stagesInput := &apigatewayv2.GetStagesInput{
+			ApiId: api.ApiId,
+		}
+		for {
+			output, err := svc.GetStages(stagesInput)
+			if err != nil {
+				return stages, err
+			}
+			for _, s := range output.Items {
+				stages = append(stages, ApiGatewayStage{
+					ApiId:         *api.ApiId,
+					ApiName:       aws.StringValue(api.Name),
+					Stage:         *s.StageName,
+					Protocol:      *api.ProtocolType,
+					Tags:          api.Tags,
+					Region:        *region,
+					DimensionTags: resource.DimensionTags,
+				})
+			}
+			if output.NextToken == nil {
+				break
+			}
+			stagesInput.NextToken = output.NextToken
+		}
+	}
+	return stages, nil
+}
+
+func GetApiGatewayStages(session *session.Session, resource *MonitoredResource) ([]ApiGatewayStage, error) {
+	stages, err := GetRestApiStages(session, resource)
+	if err != nil {
+		return stages, err
+	}
+
+	v2stages, err := GetV2ApiStages(session, resource)
+	if err != nil {
+		return stages, err
+	}
+	return append(stages, v2stages...), nil
+}
+
+func GetApiGatewayDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"api_id",
+		"api_name",
+		"stage",
+		"protocol",
+		"region",
+		"anodot-collector",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetApiGatewayMetricProperties(stage ApiGatewayStage) map[string]string {
+	properties := map[string]string{
+		"service":          "apigateway",
+		"api_id":           stage.ApiId,
+		"api_name":         stage.ApiName,
+		"stage":            stage.Stage,
+		"protocol":         stage.Protocol,
+		"region":           stage.Region,
+		"anodot-collector": "aws",
+	}
+
+	for _, dt := range stage.DimensionTags {
+		v, ok := stage.Tags[dt]
+		if !ok || v == nil {
+			continue
+		}
+		if len(dt) > 50 || len(*v) < 2 {
+			continue
+		}
+		if len(properties) == 17 {
+			break
+		}
+		properties[escape(dt)] = escape(*v)
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetApiGatewayCloudwatchMetrics(resource *MonitoredResource, stages []ApiGatewayStage) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, s := range stages {
+			name, ok := apiGatewayMetricNames[s.Protocol][mstat.Name]
+			if !ok {
+				continue
+			}
+			m := MetricToFetch{}
+			if s.Protocol == "REST" {
+				m.Dimensions = []Dimension{
+					Dimension{
+						Name:  "ApiName",
+						Value: s.ApiName,
+					},
+					Dimension{
+						Name:  "Stage",
+						Value: s.Stage,
+					},
+				}
+			} else {
+				m.Dimensions = []Dimension{
+					Dimension{
+						Name:  "ApiId",
+						Value: s.ApiId,
+					},
+					Dimension{
+						Name:  "Stage",
+						Value: s.Stage,
+					},
+				}
+			}
+			m.Resource = s
+			mstatCopy := mstat
+			mstatCopy.Name = name
+			mstatCopy.Id = "apigw" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// apiGatewayMeasurementName maps a protocol specific CloudWatch metric name back to the configured one,
+// so all protocols end up in the same schema measurement.
+func apiGatewayMeasurementName(protocol, name string) string {
+	for configured, cwname := range apiGatewayMetricNames[protocol] {
+		if cwname == name {
+			return configured
+		}
+	}
+	return name
+}
+
+func GetApiGatewayMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	stages, err := GetApiGatewayStages(ses, resource)
+	if err != nil {
+		log.Printf("Cloud not get list of API Gateway stages: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d API Gateway stages", len(stages))
+
+	metrics, err := GetApiGatewayCloudwatchMetrics(resource, stages)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) == 0 {
+		return anodotMetrics, nil
+	}
+
+	metricdatainput := NewGetMetricDataInput(metrics)
+	metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+	if err != nil {
+		log.Printf("Error during API Gateway metrics processing: %v", err)
+		return anodotMetrics, err
+	}
+
+	for _, m := range metrics {
+		for _, mr := range metricdataresults {
+			if *mr.Id == m.MStat.Id {
+				s := m.Resource.(ApiGatewayStage)
+				name := apiGatewayMeasurementName(s.Protocol, m.MStat.Name)
+				anodot_apigw_metrics := GetAnodotMetric30(name, mr.Timestamps, mr.Values, GetApiGatewayMetricProperties(s))
+				anodotMetrics = append(anodotMetrics, anodot_apigw_metrics...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
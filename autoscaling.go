@@ -0,0 +1,264 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// Tag set by Auto Scaling on every instance it launches
+const asgNameTag = "aws:autoscaling:groupName"
+
+type InstanceTypeWeight struct {
+	InstanceType string
+	Weight       float64
+}
+
+type AutoScalingGroup struct {
+	Name                 string
+	InstanceType         string
+	DesiredCapacity      int64
+	MinSize              int64
+	MaxSize              int64
+	InServiceCapacity    int64
+	Mixed                bool
+	Weights              []InstanceTypeWeight
+	OnDemandBaseCapacity int64
+	OnDemandPercentage   int64
+	Tags                 []*autoscaling.TagDescription
+	Region               string
+	DimensionTags        []string
+}
+
+func GetAutoScalingGroups(session *session.Session, resource *MonitoredResource) ([]AutoScalingGroup, error) {
+	region := session.Config.Region
+	groups := make([]AutoScalingGroup, 0)
+	svc := autoscaling.New(session)
+	rawgroups := make([]*autoscaling.Group, 0)
+
+	input := &autoscaling.DescribeAutoScalingGroupsInput{
+		MaxRecords: aws.Int64(100),
+	}
+	for {
+		output, err := svc.DescribeAutoScalingGroups(input)
+		if err != nil {
+			return groups, err
+		}
+		rawgroups = append(rawgroups, output.AutoScalingGroups...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	for _, g := range rawgroups {
+		group := AutoScalingGroup{
+			Name:            *g.AutoScalingGroupName,
+			InstanceType:    "None",
+			DesiredCapacity: aws.Int64Value(g.DesiredCapacity),
+			MinSize:         aws.Int64Value(g.MinSize),
+			MaxSize:         aws.Int64Value(g.MaxSize),
+			Weights:         make([]InstanceTypeWeight, 0),
+			// Groups without mixed instances policy are fully on-demand
+			OnDemandPercentage: 100,
+			Tags:               g.Tags,
+			Region:             *region,
+			DimensionTags:      resource.DimensionTags,
+		}
+
+		for _, i := range g.Instances {
+			if aws.StringValue(i.LifecycleState) == autoscaling.LifecycleStateInService {
+				group.InServiceCapacity++
+			}
+			if i.InstanceType != nil {
+				group.InstanceType = *i.InstanceType
+			}
+		}
+
+		if p := g.MixedInstancesPolicy; p != nil {
+			group.Mixed = true
+			group.InstanceType = "mixed"
+			if p.InstancesDistribution != nil {
+				group.OnDemandBaseCapacity = aws.Int64Value(p.InstancesDistribution.OnDemandBaseCapacity)
+				if p.InstancesDistribution.OnDemandPercentageAboveBaseCapacity != nil {
+					group.OnDemandPercentage = *p.InstancesDistribution.OnDemandPercentageAboveBaseCapacity
+				}
+			}
+			if p.LaunchTemplate != nil {
+				for _, o := range p.LaunchTemplate.Overrides {
+					if o.InstanceType == nil {
+						continue
+					}
+					weight := float64(1)
+					if o.WeightedCapacity != nil {
+						if w, err := strconv.ParseFloat(*o.WeightedCapacity, 64); err == nil {
+							weight = w
+						}
+					}
+					group.Weights = append(group.Weights, InstanceTypeWeight{InstanceType: *o.InstanceType, Weight: weight})
+				}
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func GetAutoScalingDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"asg_name",
+		"instance_type",
+		"mixed_instances",
+		"region",
+		"anodot-collector",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetAutoScalingCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "DesiredCapacity",
+			Alias:      "DesiredCapacity",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "MinSize",
+			Alias:      "MinSize",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "MaxSize",
+			Alias:      "MaxSize",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "InServiceCapacity",
+			Alias:      "InServiceCapacity",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "InstanceTypeWeight",
+			Alias:      "InstanceTypeWeight",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "OnDemandBaseCapacity",
+			Alias:      "OnDemandBaseCapacity",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "OnDemandPercentage",
+			Alias:      "OnDemandPercentage",
+			TargetType: "average",
+		},
+	}
+}
+
+func GetAutoScalingMetricProperties(g AutoScalingGroup) map[string]string {
+	properties := map[string]string{
+		"service":          "autoscaling",
+		"asg_name":         g.Name,
+		"instance_type":    g.InstanceType,
+		"mixed_instances":  strconv.FormatBool(g.Mixed),
+		"region":           g.Region,
+		"anodot-collector": "aws",
+	}
+
+	for _, v := range g.Tags {
+		for _, dt := range g.DimensionTags {
+			if *v.Key == dt {
+				if len(*v.Key) > 50 || len(*v.Value) < 2 {
+					continue
+				}
+				if len(properties) == 17 {
+					break
+				}
+				properties[escape(*v.Key)] = escape(*v.Value)
+			}
+		}
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getAutoScalingCapacityMetric(groups []AutoScalingGroup, what string) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, g := range groups {
+		var value int64
+		switch what {
+		case "DesiredCapacity":
+			value = g.DesiredCapacity
+		case "MinSize":
+			value = g.MinSize
+		case "MaxSize":
+			value = g.MaxSize
+		case "InServiceCapacity":
+			value = g.InServiceCapacity
+		case "OnDemandBaseCapacity":
+			value = g.OnDemandBaseCapacity
+		case "OnDemandPercentage":
+			value = g.OnDemandPercentage
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetAutoScalingMetricProperties(g),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{what: float64(value)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func getInstanceTypeWeightMetric(groups []AutoScalingGroup) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, g := range groups {
+		for _, w := range g.Weights {
+			properties := GetAutoScalingMetricProperties(g)
+			properties["instance_type"] = w.InstanceType
+			metric := metrics3.AnodotMetrics30{
+				Dimensions:   properties,
+				Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+				Measurements: map[string]float64{"InstanceTypeWeight": w.Weight},
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+func GetAutoScalingMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	groups, err := GetAutoScalingGroups(session, resource)
+	if err != nil {
+		log.Printf("Cloud not describe Auto Scaling groups %v", err)
+		return metrics, err
+	}
+	log.Printf("Got %d Auto Scaling groups to process", len(groups))
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			switch cm {
+			case "InstanceTypeWeight":
+				log.Printf("Processing AutoScaling custom metric InstanceTypeWeight\n")
+				metrics = append(metrics, getInstanceTypeWeightMetric(groups)...)
+			case "DesiredCapacity", "MinSize", "MaxSize", "InServiceCapacity", "OnDemandBaseCapacity", "OnDemandPercentage":
+				log.Printf("Processing AutoScaling custom metric %s\n", cm)
+				metrics = append(metrics, getAutoScalingCapacityMetric(groups, cm)...)
+			}
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/backup"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// BackupUsage holds recovery points of one vault aggregated by protected resource type (EBS, RDS, EFS...)
+type BackupUsage struct {
+	VaultName          string
+	ResourceType       string
+	RecoveryPointCount float64
+	BackupSizeBytes    float64
+	Region             string
+}
+
+func GetBackupUsage(session *session.Session) ([]BackupUsage, error) {
+	region := session.Config.Region
+	usages := make([]BackupUsage, 0)
+	svc := backup.New(session)
+
+	vaults := make([]string, 0)
+	err := svc.ListBackupVaultsPages(&backup.ListBackupVaultsInput{}, func(page *backup.ListBackupVaultsOutput, lastPage bool) bool {
+		for _, v := range page.BackupVaultList {
+			vaults = append(vaults, *v.BackupVaultName)
+		}
+		return true
+	})
+	if err != nil {
+		return usages, err
+	}
+
+	for _, vault := range vaults {
+		byType := make(map[string]*BackupUsage)
+		input := &backup.ListRecoveryPointsByBackupVaultInput{
+			BackupVaultName: aws.String(vault),
+		}
+		err := svc.ListRecoveryPointsByBackupVaultPages(input, func(page *backup.ListRecoveryPointsByBackupVaultOutput, lastPage bool) bool {
+			for _, rp := range page.RecoveryPoints {
+				if aws.StringValue(rp.Status) == backup.RecoveryPointStatusDeleting {
+					continue
+				}
+				resourceType := aws.StringValue(rp.ResourceType)
+				u, ok := byType[resourceType]
+				if !ok {
+					u = &BackupUsage{
+						VaultName:    vault,
+						ResourceType: resourceType,
+						Region:       *region,
+					}
+					byType[resourceType] = u
+				}
+				u.RecoveryPointCount++
+				u.BackupSizeBytes += float64(aws.Int64Value(rp.BackupSizeInBytes))
+			}
+			return true
+		})
+		if err != nil {
+			return usages, err
+		}
+
+		for _, u := range byType {
+			usages = append(usages, *u)
+		}
+	}
+	return usages, nil
+}
+
+func GetBackupDimensions() []string {
+	return []string{
+		"service",
+		"vault",
+		"resource_type",
+		"anodot-collector",
+		"region",
+	}
+}
+
+func GetBackupCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "RecoveryPointCount",
+			Alias:      "RecoveryPointCount",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "BackupSizeBytes",
+			Alias:      "BackupSizeBytes",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetBackupMetricProperties(u BackupUsage) map[string]string {
+	properties := map[string]string{
+		"service":          "backup",
+		"vault":            escape(u.VaultName),
+		"resource_type":    u.ResourceType,
+		"anodot-collector": "aws",
+		"region":           u.Region,
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getBackupMetric(usages []BackupUsage, what string, value func(BackupUsage) float64) []metrics3.AnodotMetrics30 {
+	metricList := make([]metrics3.AnodotMetrics30, 0)
+	for _, u := range usages {
+		metricList = append(metricList, metrics3.AnodotMetrics30{
+			Dimensions:   GetBackupMetricProperties(u),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{what: value(u)},
+		})
+	}
+	return metricList
+}
+
+func GetBackupMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+
+	usages, err := GetBackupUsage(ses)
+	if err != nil {
+		log.Printf("Cloud not list Backup recovery points: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Got %d Backup vault and resource type pairs to process", len(usages))
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "RecoveryPointCount" {
+				log.Printf("Processing Backup custom metric RecoveryPointCount\n")
+				anodotMetrics = append(anodotMetrics, getBackupMetric(usages, cm, func(u BackupUsage) float64 { return u.RecoveryPointCount })...)
+			}
+			if cm == "BackupSizeBytes" {
+				log.Printf("Processing Backup custom metric BackupSizeBytes\n")
+				anodotMetrics = append(anodotMetrics, getBackupMetric(usages, cm, func(u BackupUsage) float64 { return u.BackupSizeBytes })...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
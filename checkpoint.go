@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Checkpoints are kept in lambda bucket next to the config file, since lambda itself is stateless
+const checkpointPrefix = "usage_lambda/checkpoints/"
+
+func checkpointKey(region, name string) string {
+	return checkpointPrefix + region + "/" + name
+}
+
+// GetCheckpointData returns data saved by SaveCheckpointData, second return value is false when it does not exist yet
+func GetCheckpointData(region, name string) ([]byte, bool, error) {
+	svc := s3.New(session.New())
+	result, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(os.Getenv("lambda_bucket")),
+		Key:    aws.String(checkpointKey(region, name)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer result.Body.Close()
+
+	data, err := ioutil.ReadAll(result.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func SaveCheckpointData(region, name string, data []byte) error {
+	svc := s3.New(session.New())
+	_, err := svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(os.Getenv("lambda_bucket")),
+		Key:    aws.String(checkpointKey(region, name)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// GetCheckpoint returns time saved by SaveCheckpoint, second return value is false when checkpoint does not exist yet
+func GetCheckpoint(region, name string) (time.Time, bool, error) {
+	data, ok, err := GetCheckpointData(region, name)
+	if err != nil || !ok {
+		return time.Time{}, ok, err
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+type pendingCheckpoint struct {
+	region string
+	name   string
+	t      time.Time
+}
+
+type CheckpointList struct {
+	mux         sync.Mutex
+	checkpoints []pendingCheckpoint
+}
+
+// Checkpoints of the current lambda run, they are saved only after metrics are sent
+var checkpoints = &CheckpointList{}
+
+// Append schedules checkpoint to be saved by Save. When sending metrics fails checkpoint is not moved,
+// so the same data is fetched again by the next run.
+func (cl *CheckpointList) Append(region, name string, t time.Time) {
+	cl.mux.Lock()
+	defer cl.mux.Unlock()
+	cl.checkpoints = append(cl.checkpoints, pendingCheckpoint{region, name, t})
+}
+
+func (cl *CheckpointList) Save() error {
+	cl.mux.Lock()
+	defer cl.mux.Unlock()
+	for _, c := range cl.checkpoints {
+		if err := SaveCheckpoint(c.region, c.name, c.t); err != nil {
+			return err
+		}
+	}
+	cl.checkpoints = make([]pendingCheckpoint, 0)
+	return nil
+}
+
+func SaveCheckpoint(region, name string, t time.Time) error {
+	return SaveCheckpointData(region, name, []byte(t.UTC().Format(time.RFC3339)))
+}
@@ -5,7 +5,6 @@ import (
 	"strconv"
 
 	"github.com/anodot/anodot-common/pkg/metrics3"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudfront"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
@@ -104,9 +103,7 @@ func GetCloudfrontCloudwatchMetrics(resource *MonitoredResource, distributions [
 }
 
 func GetCloudfrontMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
-	if resource.CustomRegion != "" {
-		cloudwatchSvc = cloudwatch.New(session.Must(session.NewSession(&aws.Config{Region: aws.String("us-east-1")})))
-	}
+	cloudwatchSvc = GetHomeRegionCloudWatch("Cloudfront", cloudwatchSvc, resource)
 
 	cloudWatchFetcher := CloudWatchFetcher{
 		cloudwatchSvc: cloudwatchSvc,
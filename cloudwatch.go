@@ -7,14 +7,15 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
 
 const offset time.Duration = time.Hour
 
 type Dimension struct {
-	Name  string
-	Value string
+	Name  string `yaml:"Name"`
+	Value string `yaml:"Value"`
 }
 
 type MetricStat struct {
@@ -91,6 +92,23 @@ func NewGetMetricDataInput(mTofetch []MetricToFetch) []*cloudwatch.GetMetricData
 	return datainputs
 }
 
+// Global services publish CloudWatch metrics in their home region only
+var globalServiceHomeRegions = map[string]string{
+	"Cloudfront":        "us-east-1",
+	"Route53":           "us-east-1",
+	"GlobalAccelerator": "us-west-2",
+}
+
+// GetHomeRegionCloudWatch returns CloudWatch client of the global service home region
+// when the service is configured with Region option, otherwise cloudwatchSvc of lambda region is kept
+func GetHomeRegionCloudWatch(service string, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) *cloudwatch.CloudWatch {
+	homeRegion, ok := globalServiceHomeRegions[service]
+	if !ok || resource.CustomRegion == "" {
+		return cloudwatchSvc
+	}
+	return cloudwatch.New(session.Must(session.NewSession(&aws.Config{Region: aws.String(homeRegion)})))
+}
+
 type CloudWatchFetcher struct {
 	cloudwatchSvc *cloudwatch.CloudWatch
 }
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// Log group names are cut to this many path segments for log_group_prefix dimension (e.g. /aws/lambda)
+const defaultLogGroupPrefixDepth = 2
+
+type LogGroup struct {
+	Name          string
+	Prefix        string
+	StoredBytes   int64
+	RetentionDays int64
+	Region        string
+}
+
+func logGroupPrefix(name string, depth int) string {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	prefix := strings.Join(parts, "/")
+	if strings.HasPrefix(name, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
+
+func GetLogGroups(session *session.Session, resource *MonitoredResource) ([]LogGroup, error) {
+	region := session.Config.Region
+	groups := make([]LogGroup, 0)
+	svc := cloudwatchlogs.New(session)
+
+	depth := defaultLogGroupPrefixDepth
+	if resource.LogGroupPrefixDepth > 0 {
+		depth = resource.LogGroupPrefixDepth
+	}
+
+	input := &cloudwatchlogs.DescribeLogGroupsInput{
+		Limit: aws.Int64(50),
+	}
+	err := svc.DescribeLogGroupsPages(input, func(page *cloudwatchlogs.DescribeLogGroupsOutput, lastPage bool) bool {
+		for _, g := range page.LogGroups {
+			groups = append(groups, LogGroup{
+				Name:   *g.LogGroupName,
+				Prefix: logGroupPrefix(*g.LogGroupName, depth),
+				// Groups with retention never expire have no RetentionInDays, reported as 0
+				RetentionDays: aws.Int64Value(g.RetentionInDays),
+				StoredBytes:   aws.Int64Value(g.StoredBytes),
+				Region:        *region,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return groups, err
+	}
+	return groups, nil
+}
+
+func GetCloudWatchLogsDimensions() []string {
+	return []string{
+		"service",
+		"log_group",
+		"log_group_prefix",
+		"region",
+		"anodot-collector",
+	}
+}
+
+func GetCloudWatchLogsCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "storedBytes",
+			Alias:      "StoredBytes",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "retentionInDays",
+			Alias:      "RetentionInDays",
+			TargetType: "average",
+		},
+	}
+}
+
+// GetLogGroupMetricProperties omits log_group dimension when log groups are grouped by prefix
+func GetLogGroupMetricProperties(g LogGroup, groupByPrefix bool) map[string]string {
+	properties := map[string]string{
+		"service":          "cloudwatchlogs",
+		"log_group":        escape(g.Name),
+		"log_group_prefix": escape(g.Prefix),
+		"region":           g.Region,
+		"anodot-collector": "aws",
+	}
+	if groupByPrefix {
+		delete(properties, "log_group")
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetCloudWatchLogsCloudwatchMetrics(resource *MonitoredResource, groups []LogGroup) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, g := range groups {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "LogGroupName",
+					Value: g.Name,
+				},
+			}
+			m.Resource = g
+			mstatCopy := mstat
+			mstatCopy.Id = "logs" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+type logGroupSeries struct {
+	group LogGroup
+	what  string
+	// values per timestamp, summed up across log groups with the same prefix when GroupByPrefix is set
+	values map[time.Time]float64
+}
+
+func GetCloudWatchLogsMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	groups, err := GetLogGroups(ses, resource)
+	if err != nil {
+		log.Printf("Cloud not describe log groups: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d log groups", len(groups))
+
+	series := make(map[string]*logGroupSeries)
+	add := func(g LogGroup, what string, t time.Time, value float64) {
+		key := what + "|" + g.Name
+		if resource.GroupByPrefix {
+			key = what + "|" + g.Prefix
+		}
+		s, ok := series[key]
+		if !ok {
+			s = &logGroupSeries{group: g, what: what, values: make(map[time.Time]float64)}
+			series[key] = s
+		}
+		s.values[t] += value
+	}
+
+	metrics, err := GetCloudWatchLogsCloudwatchMetrics(resource, groups)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during CloudWatch Logs metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					g := m.Resource.(LogGroup)
+					for i := range mr.Values {
+						add(g, m.MStat.Name, *mr.Timestamps[i], *mr.Values[i])
+					}
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	groupsPerPrefix := make(map[string]int)
+	for _, g := range groups {
+		groupsPerPrefix[g.Prefix]++
+	}
+
+	for _, cm := range resource.CustomMetrics {
+		if cm == "StoredBytes" || cm == "storedBytes" {
+			log.Printf("Processing CloudWatchLogs custom metric StoredBytes\n")
+			for _, g := range groups {
+				add(g, "storedBytes", now, float64(g.StoredBytes))
+			}
+		}
+		if cm == "RetentionInDays" || cm == "retentionInDays" {
+			log.Printf("Processing CloudWatchLogs custom metric RetentionInDays\n")
+			for _, g := range groups {
+				value := float64(g.RetentionDays)
+				// retention is averaged over the prefix when log groups are grouped
+				if resource.GroupByPrefix {
+					value = value / float64(groupsPerPrefix[g.Prefix])
+				}
+				add(g, "retentionInDays", now, value)
+			}
+		}
+	}
+
+	for _, s := range series {
+		properties := GetLogGroupMetricProperties(s.group, resource.GroupByPrefix)
+		for t, v := range s.values {
+			anodotMetrics = append(anodotMetrics, metrics3.AnodotMetrics30{
+				Dimensions:   properties,
+				Timestamp:    metrics3.AnodotTimestamp{t},
+				Measurements: map[string]float64{s.what: v},
+			})
+		}
+	}
+	return anodotMetrics, nil
+}
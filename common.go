@@ -21,6 +21,26 @@ func GetSupportedService() []string {
 		"DynamoDB",
 		"Kinesis",
 		"ElastiCache",
+		"APIGateway",
+		"Redshift",
+		"OpenSearch",
+		"Firehose",
+		"MSK",
+		"Network",
+		"EBSSnapshots",
+		"AutoScaling",
+		"ServiceQuotas",
+		"CostExplorer",
+		"Reservations",
+		"CloudWatchLogs",
+		"Generic",
+		"DataPipeline",
+		"SageMaker",
+		"FSx",
+		"Backup",
+		"Route53",
+		"GlobalAccelerator",
+		"Waste",
 	}
 }
 
@@ -42,6 +62,24 @@ func removeDuplicates(list []string) []string {
 	return new
 }
 
+// ageBucket returns age of a resource or object since start as one of a few ranges used as dimension value
+func ageBucket(start time.Time) string {
+	age := time.Since(start)
+	day := 24 * time.Hour
+	switch {
+	case age < 7*day:
+		return "0-7d"
+	case age < 30*day:
+		return "7-30d"
+	case age < 90*day:
+		return "30-90d"
+	case age < 365*day:
+		return "90-365d"
+	default:
+		return "365d+"
+	}
+}
+
 func escape(s string) string {
 	return strings.ReplaceAll(s, ":", "_")
 }
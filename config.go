@@ -28,11 +28,27 @@ type Tag struct {
 }
 
 type MonitoredResource struct {
-	Tags          []Tag
-	DimensionTags []string     `yaml:"DimensionsFromTags,omitempty"`
-	Metrics       []MetricStat `yaml:"CloudWatchMetrics"`
-	CustomMetrics []string     `yaml:"CustomMetrics"`
-	CustomRegion  string       `yaml:"Region,omitempty"`
+	Tags                 []Tag
+	DimensionTags        []string          `yaml:"DimensionsFromTags,omitempty"`
+	Metrics              []MetricStat      `yaml:"CloudWatchMetrics"`
+	CustomMetrics        []string          `yaml:"CustomMetrics"`
+	CustomRegion         string            `yaml:"Region,omitempty"`
+	CostAllocationTag    string            `yaml:"CostAllocationTag,omitempty"`
+	TrailingDays         int               `yaml:"TrailingDays,omitempty"`
+	LogGroupPrefixDepth  int               `yaml:"LogGroupPrefixDepth,omitempty"`
+	GroupByPrefix        bool              `yaml:"GroupByPrefix,omitempty"`
+	DimensionSets        [][]Dimension     `yaml:"DimensionSets,omitempty"`
+	DiscoverDimensions   []string          `yaml:"DiscoverDimensions,omitempty"`
+	RenameDimensions     map[string]string `yaml:"RenameDimensions,omitempty"`
+	DetailedAttributes   bool              `yaml:"DetailedAttributes,omitempty"`
+	PriceTable           string            `yaml:"PriceTable,omitempty"`
+	IdleHours            int               `yaml:"IdleHours,omitempty"`
+	CreateRequestMetrics bool              `yaml:"CreateRequestMetrics,omitempty"`
+	ReportLocation       string            `yaml:"ReportLocation,omitempty"`
+	InventoryPrefix      string            `yaml:"InventoryPrefix,omitempty"`
+	StorageLensPrefix    string            `yaml:"StorageLensPrefix,omitempty"`
+	ReportPrefixDepth    int               `yaml:"ReportPrefixDepth,omitempty"`
+	ReportFormat         string            `yaml:"ReportFormat,omitempty"`
 }
 
 type MetricFunction func(*session.Session, *cloudwatch.CloudWatch, *MonitoredResource) ([]metrics3.AnodotMetrics30, error)
@@ -68,6 +84,46 @@ func GetMetricsFunction(servicName string) MetricFunction {
 		return GetKinesisMetrics30
 	case "ElastiCache":
 		return GetElasticacheMetrics30
+	case "APIGateway":
+		return GetApiGatewayMetrics30
+	case "Redshift":
+		return GetRedshiftMetrics30
+	case "OpenSearch":
+		return GetOpenSearchMetrics30
+	case "Firehose":
+		return GetFirehoseMetrics30
+	case "MSK":
+		return GetMskMetrics30
+	case "Network":
+		return GetNetworkMetrics30
+	case "EBSSnapshots":
+		return GetEBSSnapshotsMetrics30
+	case "AutoScaling":
+		return GetAutoScalingMetrics30
+	case "ServiceQuotas":
+		return GetServiceQuotasMetrics30
+	case "CostExplorer":
+		return GetCostExplorerMetrics30
+	case "Reservations":
+		return GetReservationsMetrics30
+	case "CloudWatchLogs":
+		return GetCloudWatchLogsMetrics30
+	case "Generic":
+		return GetGenericMetrics30
+	case "DataPipeline":
+		return GetDataPipelineMetrics30
+	case "SageMaker":
+		return GetSageMakerMetrics30
+	case "FSx":
+		return GetFSxMetrics30
+	case "Backup":
+		return GetBackupMetrics30
+	case "Route53":
+		return GetRoute53Metrics30
+	case "GlobalAccelerator":
+		return GetGlobalAcceleratorMetrics30
+	case "Waste":
+		return GetWasteMetrics30
 	}
 	return nil
 }
@@ -121,6 +177,14 @@ func GetConfig() (Config, error) {
 		return c, err
 	}
 
+	for region, services := range c.RegionsConfigs {
+		if resource, ok := services["S3"]; ok && resource != nil {
+			if err := ValidateReportFormat(resource); err != nil {
+				return c, fmt.Errorf("S3 config of %s: %v", region, err)
+			}
+		}
+	}
+
 	if anodotUrl != "" {
 		c.AnodotUrl = anodotUrl
 	}
@@ -147,6 +147,38 @@ var cloudwatchMetrics = map[string]map[string]CloudWatchMetric{
 			Id:        "test1",
 			Stat:      "Sum",
 		},
+		"ConsumedLCUs": CloudWatchMetric{
+			Name:      "ConsumedLCUs",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ELB",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"NewFlowCount": CloudWatchMetric{
+			Name:      "NewFlowCount",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ELB",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"HealthyHostCount": CloudWatchMetric{
+			Name:      "HealthyHostCount",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ELB",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+		"TargetResponseTime": CloudWatchMetric{
+			Name:      "TargetResponseTime",
+			Period:    "3600",
+			Unit:      "Seconds",
+			Namespace: "AWS/ELB",
+			Id:        "test1",
+			Stat:      "Average",
+		},
 	},
 	"S3": map[string]CloudWatchMetric{
 		"ListRequests": CloudWatchMetric{
@@ -266,4 +298,254 @@ var cloudwatchMetrics = map[string]map[string]CloudWatchMetric{
 			Stat:      "Average",
 		},
 	},
+	"APIGateway": map[string]CloudWatchMetric{
+		"Count": CloudWatchMetric{
+			Name:      "Count",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ApiGateway",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"4XXError": CloudWatchMetric{
+			Name:      "4XXError",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ApiGateway",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"5XXError": CloudWatchMetric{
+			Name:      "5XXError",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ApiGateway",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"Latency": CloudWatchMetric{
+			Name:      "Latency",
+			Period:    "3600",
+			Unit:      "Milliseconds",
+			Namespace: "AWS/ApiGateway",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+		"DataProcessed": CloudWatchMetric{
+			Name:      "DataProcessed",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/ApiGateway",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"Redshift": map[string]CloudWatchMetric{
+		"PercentageDiskSpaceUsed": CloudWatchMetric{
+			Name:      "PercentageDiskSpaceUsed",
+			Period:    "3600",
+			Unit:      "Percent",
+			Namespace: "AWS/Redshift",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+		"ConcurrencyScalingSeconds": CloudWatchMetric{
+			Name:      "ConcurrencyScalingSeconds",
+			Period:    "3600",
+			Unit:      "Seconds",
+			Namespace: "AWS/Redshift",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"OpenSearch": map[string]CloudWatchMetric{
+		"FreeStorageSpace": CloudWatchMetric{
+			Name:      "FreeStorageSpace",
+			Period:    "3600",
+			Unit:      "Megabytes",
+			Namespace: "AWS/ES",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+		"SearchableDocuments": CloudWatchMetric{
+			Name:      "SearchableDocuments",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/ES",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+	},
+	"Firehose": map[string]CloudWatchMetric{
+		"IncomingBytes": CloudWatchMetric{
+			Name:      "IncomingBytes",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/Firehose",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"DeliveryToS3.Bytes": CloudWatchMetric{
+			Name:      "DeliveryToS3.Bytes",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/Firehose",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"MSK": map[string]CloudWatchMetric{
+		"BytesInPerSec": CloudWatchMetric{
+			Name:      "BytesInPerSec",
+			Period:    "3600",
+			Unit:      "Bytes/Second",
+			Namespace: "AWS/Kafka",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+	},
+	"Network": map[string]CloudWatchMetric{
+		"BytesIn": CloudWatchMetric{
+			Name:      "BytesIn",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/TransitGateway",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"BytesOut": CloudWatchMetric{
+			Name:      "BytesOut",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/TransitGateway",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"CloudWatchLogs": map[string]CloudWatchMetric{
+		"IncomingBytes": CloudWatchMetric{
+			Name:      "IncomingBytes",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/Logs",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"IncomingLogEvents": CloudWatchMetric{
+			Name:      "IncomingLogEvents",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/Logs",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"DataPipeline": map[string]CloudWatchMetric{
+		"ExecutionsStarted": CloudWatchMetric{
+			Name:      "ExecutionsStarted",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/States",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"ConsumedCapacity": CloudWatchMetric{
+			Name:      "ConsumedCapacity",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/States",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"ProcessedBytes": CloudWatchMetric{
+			Name:      "ProcessedBytes",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/Athena",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"SageMaker": map[string]CloudWatchMetric{
+		"Invocations": CloudWatchMetric{
+			Name:      "Invocations",
+			Period:    "3600",
+			Unit:      "None",
+			Namespace: "AWS/SageMaker",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"ModelLatency": CloudWatchMetric{
+			Name:      "ModelLatency",
+			Period:    "3600",
+			Unit:      "Microseconds",
+			Namespace: "AWS/SageMaker",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+	},
+	"FSx": map[string]CloudWatchMetric{
+		"FreeStorageCapacity": CloudWatchMetric{
+			Name:      "FreeStorageCapacity",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/FSx",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+	},
+	"Route53": map[string]CloudWatchMetric{
+		"DNSQueries": CloudWatchMetric{
+			Name:      "DNSQueries",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/Route53",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"GlobalAccelerator": map[string]CloudWatchMetric{
+		"ProcessedBytesIn": CloudWatchMetric{
+			Name:      "ProcessedBytesIn",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/GlobalAccelerator",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"ProcessedBytesOut": CloudWatchMetric{
+			Name:      "ProcessedBytesOut",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/GlobalAccelerator",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+	},
+	"EBS": map[string]CloudWatchMetric{
+		"VolumeReadBytes": CloudWatchMetric{
+			Name:      "VolumeReadBytes",
+			Period:    "3600",
+			Unit:      "Bytes",
+			Namespace: "AWS/EBS",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"VolumeWriteOps": CloudWatchMetric{
+			Name:      "VolumeWriteOps",
+			Period:    "3600",
+			Unit:      "Count",
+			Namespace: "AWS/EBS",
+			Id:        "test1",
+			Stat:      "Sum",
+		},
+		"BurstBalance": CloudWatchMetric{
+			Name:      "BurstBalance",
+			Period:    "3600",
+			Unit:      "Percent",
+			Namespace: "AWS/EBS",
+			Id:        "test1",
+			Stat:      "Average",
+		},
+	},
 }
@@ -23,30 +23,63 @@ var metricsButtons = []string{"Default (All metrics above)", "Done"}
 
 var metrics = map[string][]string{
 	"ElastiCache": []string{"CacheNodesCount", "CPUUtilization"},
-	"EC2":         []string{"CoreCount", "VCpuCount", "NetworkOut", "NetworkIn"},
-	"EBS":         []string{"Size"},
+	"EC2":         []string{"CoreCount", "VCpuCount", "MemoryGiB", "InstanceHours", "HourlyPrice", "EstimatedCost", "NetworkOut", "NetworkIn"},
+	"EBS":         []string{"Size", "Throughput", "VolumeReadBytes", "VolumeWriteOps", "BurstBalance"},
 	"S3": []string{"BucketSizeBytes", "NumberOfObjects", "AllRequests", "GetRequests",
 		"PutRequests", "DeleteRequests", "HeadRequests",
 		"SelectRequests", "ListRequests"},
 	"Cloudfront": []string{"BytesDownloaded", "Requests", "TotalErrorRate"},
-	"ELB":        []string{"RequestCount", "EstimatedProcessedBytes"},
+	"ELB":        []string{"AzCount", "RequestCount", "NewFlowCount", "EstimatedProcessedBytes", "ConsumedLCUs", "HealthyHostCount", "TargetResponseTime"},
 	"NatGateway": []string{"BytesOutToSource", "BytesOutToDestination", "BytesInFromSource",
 		"BytesInFromDestination", "ActiveConnectionCount",
 		"ConnectionEstablishedCount"},
 	"Efs": []string{"Size_All", "Size_Infrequent", "Size_Standard", "DataWriteIOBytes", "DataReadIOBytes"},
 	"DynamoDB": []string{"SuccessfulRequestLatency", "ReturnedItemCount", "ConsumedWriteCapacityUnits",
 		"ProvisionedWriteCapacityUnits", "ConsumedReadCapacityUnits", "ProvisionedReadCapacityUnits"},
+	"APIGateway":        []string{"Count", "4XXError", "5XXError", "Latency", "DataProcessed"},
+	"Redshift":          []string{"NodesCount", "PercentageDiskSpaceUsed", "ConcurrencyScalingSeconds"},
+	"OpenSearch":        []string{"InstanceCount", "VolumeSize", "FreeStorageSpace", "SearchableDocuments"},
+	"Firehose":          []string{"IncomingBytes", "DeliveryToS3.Bytes"},
+	"MSK":               []string{"BrokersCount", "BrokerStorage", "BytesInPerSec"},
+	"Network":           []string{"ElasticIpCount", "VpcEndpointCount", "BytesIn", "BytesOut"},
+	"EBSSnapshots":      []string{"Size", "Count"},
+	"AutoScaling":       []string{"DesiredCapacity", "MinSize", "MaxSize", "InServiceCapacity", "InstanceTypeWeight", "OnDemandBaseCapacity", "OnDemandPercentage"},
+	"ServiceQuotas":     []string{"quota_limit", "quota_used", "quota_utilization_pct"},
+	"CostExplorer":      []string{"UnblendedCost", "AmortizedCost", "UsageQuantity"},
+	"Reservations":      []string{"RIUtilization", "RICoverage", "SavingsPlansUtilization", "SavingsPlansCoverage"},
+	"CloudWatchLogs":    []string{"IncomingBytes", "IncomingLogEvents", "StoredBytes", "RetentionInDays"},
+	"DataPipeline":      []string{"ExecutionsStarted", "ConsumedCapacity", "ProcessedBytes", "GlueDPUSeconds"},
+	"SageMaker":         []string{"Invocations", "ModelLatency", "InstanceCount"},
+	"FSx":               []string{"FreeStorageCapacity", "StorageCapacity", "ThroughputCapacity"},
+	"Backup":            []string{"RecoveryPointCount", "BackupSizeBytes"},
+	"Route53":           []string{"DNSQueries", "RecordSetCount", "HealthCheckCount"},
+	"GlobalAccelerator": []string{"ProcessedBytesIn", "ProcessedBytesOut", "AcceleratorCount"},
+	"Waste":             []string{"IdleResource"},
 }
 
 var servicesWithTags = map[string]bool{
-	"EC2":        true,
-	"EBS":        true,
-	"Efs":        true,
-	"ELB":        true,
-	"NatGateway": true,
+	"EC2":          true,
+	"EBS":          true,
+	"Efs":          true,
+	"ELB":          true,
+	"NatGateway":   true,
+	"APIGateway":   true,
+	"Redshift":     true,
+	"Network":      true,
+	"EBSSnapshots": true,
+	"AutoScaling":  true,
+	"DataPipeline": true,
+	"FSx":          true,
 }
 
-var services = []string{"EC2", "EBS", "S3", "NatGateway", "ELB", "Efs", "DynamoDB", "Cloudfront", "ElastiCache", "Default (All services above)", "Done"}
+// accountWideServices report data of the whole account, they are configured in one region only
+// and are not part of the default set
+var accountWideServices = map[string]bool{
+	"CostExplorer": true,
+	"Reservations": true,
+}
+
+var services = []string{"EC2", "EBS", "S3", "NatGateway", "ELB", "Efs", "DynamoDB", "Cloudfront", "ElastiCache", "APIGateway", "Redshift", "OpenSearch", "Firehose", "MSK", "Network", "EBSSnapshots", "AutoScaling", "ServiceQuotas", "CostExplorer", "Reservations", "CloudWatchLogs", "DataPipeline", "SageMaker", "FSx", "Backup", "Route53", "GlobalAccelerator", "Waste", "Default (All services above)", "Done"}
 
 var regions = []string{
 	"eu-north-1",
@@ -213,15 +246,34 @@ func removeDuplicatesMetrics(list []string) []string {
 	return new
 }
 
-func removeCloudfront(list []string) []string {
+// globalServices are not regional, they are configured in the first region only
+var globalServices = []string{"Cloudfront", "Route53", "GlobalAccelerator"}
+
+func isGlobalService(name string) bool {
+	for _, s := range globalServices {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func removeGlobalServices(list []string) []string {
+	for _, s := range globalServices {
+		list = removeService(list, s)
+	}
+	return list
+}
+
+func removeService(list []string, name string) []string {
 	services := make([]string, 0)
 
 	for _, s := range list {
-		if s != "Cloudfront" {
+		if s != name {
 			services = append(services, s)
 		}
 	}
-	delete(metrics, "Cloudfront")
+	delete(metrics, name)
 	return services
 }
 
@@ -364,12 +416,15 @@ func ChoseService(region string) ([]ServiceN, error) {
 			}
 
 			for _, srv := range GetAllMetricsAllServices() {
+				if accountWideServices[srv.Name] {
+					continue
+				}
 				if _, ok := servicesWithTags[srv.Name]; ok {
 					srv.Tags = tags
 				}
 				chosenservices = append(chosenservices, srv)
 			}
-			services = removeCloudfront(services)
+			services = removeGlobalServices(services)
 			return chosenservices, nil
 		}
 
@@ -394,8 +449,8 @@ func ChoseService(region string) ([]ServiceN, error) {
 			}
 		}
 
-		if service == "Cloudfront" {
-			services = removeCloudfront(services)
+		if isGlobalService(service) || accountWideServices[service] {
+			services = removeService(services, service)
 		}
 
 		tags, err := SetDimenionsTags()
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+const costExplorerDateFormat = "2006-01-02"
+
+// Cost Explorer keeps adjusting costs for a few days, so they are re-fetched for this many days by default
+const defaultCostTrailingDays = 3
+
+const costExplorerCheckpoint = "CostExplorer"
+
+// Costs grouped by cost allocation tag are reported as separate measurements (e.g. unblended_cost_by_tag),
+// they are the same costs as grouped by usage type, so summing both would count them twice
+const costByTagSuffix = "_by_tag"
+
+var costMeasurements = map[string]string{
+	"UnblendedCost": "unblended_cost",
+	"AmortizedCost": "amortized_cost",
+	"UsageQuantity": "usage_quantity",
+}
+
+type DailyCost struct {
+	Day         time.Time
+	Service     string
+	UsageType   string
+	TagKey      string
+	TagValue    string
+	Measurement string
+	Amount      float64
+}
+
+// Cost Explorer API is served from us-east-1 only
+func newCostExplorerClient(ses *session.Session) *costexplorer.CostExplorer {
+	return costexplorer.New(ses, aws.NewConfig().WithRegion("us-east-1"))
+}
+
+// CostExplorer API supports at most two group by keys, so service and usage type
+// and service and cost allocation tag are fetched by separate queries
+func GetCostAndUsage(svc *costexplorer.CostExplorer, start, end time.Time, groupBy []*costexplorer.GroupDefinition, metricNames []string) ([]DailyCost, error) {
+	costs := make([]DailyCost, 0)
+
+	input := &costexplorer.GetCostAndUsageInput{
+		Granularity: aws.String(costexplorer.GranularityDaily),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start.Format(costExplorerDateFormat)),
+			End:   aws.String(end.Format(costExplorerDateFormat)),
+		},
+		GroupBy: groupBy,
+		Metrics: aws.StringSlice(metricNames),
+	}
+
+	for {
+		output, err := svc.GetCostAndUsage(input)
+		if err != nil {
+			return costs, err
+		}
+
+		for _, r := range output.ResultsByTime {
+			day, err := time.Parse(costExplorerDateFormat, *r.TimePeriod.Start)
+			if err != nil {
+				return costs, err
+			}
+
+			for _, g := range r.Groups {
+				cost := DailyCost{
+					Day: day,
+				}
+				for i, key := range g.Keys {
+					switch *groupBy[i].Key {
+					case costexplorer.DimensionService:
+						cost.Service = *key
+					case costexplorer.DimensionUsageType:
+						cost.UsageType = *key
+					default:
+						// Tag group keys are returned as "<tag key>$<tag value>"
+						cost.TagKey = *groupBy[i].Key
+						cost.TagValue = strings.TrimPrefix(*key, cost.TagKey+"$")
+					}
+				}
+
+				for name, value := range g.Metrics {
+					amount, err := strconv.ParseFloat(aws.StringValue(value.Amount), 64)
+					if err != nil {
+						continue
+					}
+					c := cost
+					c.Measurement = costMeasurements[name]
+					if c.TagKey != "" {
+						c.Measurement += costByTagSuffix
+					}
+					c.Amount = amount
+					costs = append(costs, c)
+				}
+			}
+		}
+
+		if output.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+	return costs, nil
+}
+
+func GetCostExplorerDimensions() []string {
+	return []string{
+		"service",
+		"aws_service",
+		"usage_type",
+		"cost_tag_key",
+		"cost_tag",
+		"anodot-collector",
+	}
+}
+
+func GetCostExplorerCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "unblended_cost",
+			Alias:      "UnblendedCost",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "amortized_cost",
+			Alias:      "AmortizedCost",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "usage_quantity",
+			Alias:      "UsageQuantity",
+			TargetType: "sum",
+		},
+	}
+}
+
+// GetCostExplorerSchemaMetrics returns custom metrics and, when costs are grouped by cost allocation tag,
+// their by tag variants. Variant has the alias of its base metric, so configuring e.g. UnblendedCost
+// adds both unblended_cost and unblended_cost_by_tag to the schema.
+func GetCostExplorerSchemaMetrics(resource *MonitoredResource) []CustomMetricDefinition {
+	metrics := GetCostExplorerCustomMetrics()
+	if resource.CostAllocationTag == "" {
+		return metrics
+	}
+	for _, def := range GetCostExplorerCustomMetrics() {
+		metrics = append(metrics, CustomMetricDefinition{
+			Name:       def.Name + costByTagSuffix,
+			Alias:      def.Alias,
+			TargetType: def.TargetType,
+		})
+	}
+	return metrics
+}
+
+// truncate keeps long Cost Explorer names (e.g. "Amazon Elastic Compute Cloud - Compute") instead of dropping them
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+func GetCostExplorerMetricProperties(c DailyCost) map[string]string {
+	properties := map[string]string{
+		"service":          "costexplorer",
+		"aws_service":      truncate(escape(c.Service), 50),
+		"usage_type":       truncate(escape(c.UsageType), 50),
+		"cost_tag_key":     escape(c.TagKey),
+		"cost_tag":         escape(c.TagValue),
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetCostExplorerMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	region := *ses.Config.Region
+
+	metricNames := make([]string, 0)
+	for _, cm := range resource.CustomMetrics {
+		for _, def := range GetCostExplorerCustomMetrics() {
+			if cm == def.Name || cm == def.Alias {
+				metricNames = append(metricNames, def.Alias)
+			}
+		}
+	}
+	if len(metricNames) == 0 {
+		return anodotMetrics, nil
+	}
+
+	trailingDays := defaultCostTrailingDays
+	if resource.TrailingDays > 0 {
+		trailingDays = resource.TrailingDays
+	}
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	start := end.AddDate(0, 0, -trailingDays)
+
+	checkpoint, ok, err := GetCheckpoint(region, costExplorerCheckpoint)
+	if err != nil {
+		log.Printf("Cloud not read Cost Explorer checkpoint: %v", err)
+		return anodotMetrics, err
+	}
+	if ok {
+		// Days after the last fetched one (if lambda was not running) and the trailing window are fetched
+		from := checkpoint.AddDate(0, 0, -trailingDays)
+		if from.Before(start) {
+			start = from
+		}
+	}
+	if !start.Before(end) {
+		return anodotMetrics, nil
+	}
+
+	svc := newCostExplorerClient(ses)
+
+	groups := [][]*costexplorer.GroupDefinition{
+		{
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionService)},
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionUsageType)},
+		},
+	}
+	if resource.CostAllocationTag != "" {
+		groups = append(groups, []*costexplorer.GroupDefinition{
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionService)},
+			{Type: aws.String(costexplorer.GroupDefinitionTypeTag), Key: aws.String(resource.CostAllocationTag)},
+		})
+	}
+
+	for _, groupBy := range groups {
+		costs, err := GetCostAndUsage(svc, start, end, groupBy, metricNames)
+		if err != nil {
+			log.Printf("Cloud not get cost and usage: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, c := range costs {
+			metric := metrics3.AnodotMetrics30{
+				Dimensions:   GetCostExplorerMetricProperties(c),
+				Timestamp:    metrics3.AnodotTimestamp{c.Day},
+				Measurements: map[string]float64{c.Measurement: c.Amount},
+			}
+			anodotMetrics = append(anodotMetrics, metric)
+		}
+	}
+	log.Printf("Got Cost Explorer data from %s to %s", start.Format(costExplorerDateFormat), end.Format(costExplorerDateFormat))
+
+	checkpoints.Append(region, costExplorerCheckpoint, end)
+	return anodotMetrics, nil
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	stateMachineResource     = "state_machine"
+	glueJobResource          = "glue_job"
+	athenaWorkgroupResource  = "athena_workgroup"
+	glueJobRunsCheckpoint    = "DataPipelineGlue"
+	stateTransitionMetric    = "ConsumedCapacity"
+	statesNamespace          = "AWS/States"
+	athenaNamespace          = "AWS/Athena"
+	glueJobDefaultTimeout    = 48 * time.Hour
+	glueDpuSecondsMeasurment = "DPUSeconds"
+)
+
+// Athena publishes metrics per workgroup, query state and query type
+var athenaDimensionKeys = []string{"QueryState", "QueryType", "WorkGroup"}
+
+type PipelineResource struct {
+	Type          string
+	Name          string
+	Arn           string
+	WorkerType    string
+	Tags          map[string]string
+	Region        string
+	DimensionTags []string
+}
+
+type GlueJobRun struct {
+	Job        PipelineResource
+	WorkerType string
+	Completed  time.Time
+	DPUSeconds float64
+}
+
+// Glue and Athena resources have no ARN in list responses, it is built from account of the caller
+func getCallerArn(session *session.Session) (arn.ARN, error) {
+	identity, err := sts.New(session).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return arn.ARN{}, err
+	}
+	return arn.Parse(*identity.Arn)
+}
+
+func GetStateMachines(session *session.Session, resource *MonitoredResource) ([]PipelineResource, error) {
+	region := session.Config.Region
+	machines := make([]PipelineResource, 0)
+	svc := sfn.New(session)
+
+	err := svc.ListStateMachinesPages(&sfn.ListStateMachinesInput{}, func(page *sfn.ListStateMachinesOutput, lastPage bool) bool {
+		for _, sm := range page.StateMachines {
+			machines = append(machines, PipelineResource{
+				Type:          stateMachineResource,
+				Name:          *sm.Name,
+				Arn:           *sm.StateMachineArn,
+				WorkerType:    aws.StringValue(sm.Type),
+				Tags:          make(map[string]string),
+				Region:        *region,
+				DimensionTags: resource.DimensionTags,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return machines, err
+	}
+
+	if len(resource.DimensionTags) > 0 {
+		for i := range machines {
+			output, err := svc.ListTagsForResource(&sfn.ListTagsForResourceInput{
+				ResourceArn: aws.String(machines[i].Arn),
+			})
+			if err != nil {
+				return machines, err
+			}
+			for _, t := range output.Tags {
+				machines[i].Tags[*t.Key] = *t.Value
+			}
+		}
+	}
+	return machines, nil
+}
+
+func GetGlueJobs(session *session.Session, resource *MonitoredResource, caller arn.ARN) ([]PipelineResource, error) {
+	region := session.Config.Region
+	jobs := make([]PipelineResource, 0)
+	svc := glue.New(session)
+
+	err := svc.GetJobsPages(&glue.GetJobsInput{}, func(page *glue.GetJobsOutput, lastPage bool) bool {
+		for _, j := range page.Jobs {
+			jobs = append(jobs, PipelineResource{
+				Type: glueJobResource,
+				Name: *j.Name,
+				Arn: arn.ARN{
+					Partition: caller.Partition,
+					Service:   "glue",
+					Region:    *region,
+					AccountID: caller.AccountID,
+					Resource:  "job/" + *j.Name,
+				}.String(),
+				WorkerType:    aws.StringValue(j.WorkerType),
+				Tags:          make(map[string]string),
+				Region:        *region,
+				DimensionTags: resource.DimensionTags,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return jobs, err
+	}
+
+	if len(resource.DimensionTags) > 0 {
+		for i := range jobs {
+			output, err := svc.GetTags(&glue.GetTagsInput{
+				ResourceArn: aws.String(jobs[i].Arn),
+			})
+			if err != nil {
+				return jobs, err
+			}
+			for k, v := range output.Tags {
+				jobs[i].Tags[k] = aws.StringValue(v)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func GetAthenaWorkgroups(session *session.Session, resource *MonitoredResource, caller arn.ARN) ([]PipelineResource, error) {
+	region := session.Config.Region
+	workgroups := make([]PipelineResource, 0)
+	svc := athena.New(session)
+
+	err := svc.ListWorkGroupsPages(&athena.ListWorkGroupsInput{}, func(page *athena.ListWorkGroupsOutput, lastPage bool) bool {
+		for _, wg := range page.WorkGroups {
+			if aws.StringValue(wg.State) != athena.WorkGroupStateEnabled {
+				continue
+			}
+			workgroups = append(workgroups, PipelineResource{
+				Type: athenaWorkgroupResource,
+				Name: *wg.Name,
+				Arn: arn.ARN{
+					Partition: caller.Partition,
+					Service:   "athena",
+					Region:    *region,
+					AccountID: caller.AccountID,
+					Resource:  "workgroup/" + *wg.Name,
+				}.String(),
+				Tags:          make(map[string]string),
+				Region:        *region,
+				DimensionTags: resource.DimensionTags,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return workgroups, err
+	}
+
+	if len(resource.DimensionTags) > 0 {
+		for i := range workgroups {
+			output, err := svc.ListTagsForResource(&athena.ListTagsForResourceInput{
+				ResourceARN: aws.String(workgroups[i].Arn),
+			})
+			if err != nil {
+				return workgroups, err
+			}
+			for _, t := range output.Tags {
+				workgroups[i].Tags[*t.Key] = *t.Value
+			}
+		}
+	}
+	return workgroups, nil
+}
+
+// GetGlueJobRuns returns runs completed after since and not after until. Runs are listed from the newest one,
+// so paging stops at runs started before since minus default job timeout
+func GetGlueJobRuns(session *session.Session, jobs []PipelineResource, since, until time.Time) ([]GlueJobRun, error) {
+	runs := make([]GlueJobRun, 0)
+	svc := glue.New(session)
+	startedAfter := since.Add(-glueJobDefaultTimeout)
+
+	for _, j := range jobs {
+		input := &glue.GetJobRunsInput{
+			JobName: aws.String(j.Name),
+		}
+		err := svc.GetJobRunsPages(input, func(page *glue.GetJobRunsOutput, lastPage bool) bool {
+			for _, r := range page.JobRuns {
+				if r.StartedOn != nil && r.StartedOn.Before(startedAfter) {
+					return false
+				}
+				// Runs completed after until are counted by the next lambda run
+				if r.CompletedOn == nil || !r.CompletedOn.After(since) || r.CompletedOn.After(until) {
+					continue
+				}
+				// MaxCapacity is number of DPUs allocated for the run, for both standard and G.X workers
+				runs = append(runs, GlueJobRun{
+					Job:        j,
+					WorkerType: aws.StringValue(r.WorkerType),
+					Completed:  *r.CompletedOn,
+					DPUSeconds: float64(aws.Int64Value(r.ExecutionTime)) * aws.Float64Value(r.MaxCapacity),
+				})
+			}
+			return true
+		})
+		if err != nil {
+			return runs, err
+		}
+	}
+	return runs, nil
+}
+
+func GetDataPipelineDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"resource_type",
+		"resource_name",
+		"worker_type",
+		"query_state",
+		"query_type",
+		"region",
+		"anodot-collector",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetDataPipelineCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       glueDpuSecondsMeasurment,
+			Alias:      "GlueDPUSeconds",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetDataPipelineMetricProperties(r PipelineResource) map[string]string {
+	properties := map[string]string{
+		"service":          "datapipeline",
+		"resource_type":    r.Type,
+		"resource_name":    escape(r.Name),
+		"worker_type":      r.WorkerType,
+		"region":           r.Region,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range r.Tags {
+		for _, dt := range r.DimensionTags {
+			if k == dt {
+				if len(k) > 50 || len(v) < 2 {
+					continue
+				}
+				if len(properties) == 17 {
+					break
+				}
+				properties[escape(k)] = escape(v)
+			}
+		}
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+// GetDataPipelineCloudwatchMetrics routes configured metrics by namespace: AWS/States metrics are fetched per state machine
+// (except state transitions which are account wide), AWS/Athena metrics per workgroup, query state and query type
+func GetDataPipelineCloudwatchMetrics(cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource, region string, machines, workgroups []PipelineResource) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	add := func(mstat MetricStat, r PipelineResource, dims []Dimension) {
+		m := MetricToFetch{}
+		m.Dimensions = dims
+		m.Resource = r
+		mstatCopy := mstat
+		mstatCopy.Id = "pipeline" + strconv.Itoa(len(metrics))
+		m.MStat = mstatCopy
+		metrics = append(metrics, m)
+	}
+
+	for _, mstat := range resource.Metrics {
+		switch mstat.Namespace {
+		case statesNamespace:
+			if mstat.Name == stateTransitionMetric {
+				account := PipelineResource{
+					Type:   stateMachineResource,
+					Region: region,
+				}
+				add(mstat, account, []Dimension{
+					Dimension{
+						Name:  "ServiceMetric",
+						Value: "StateTransition",
+					},
+				})
+				continue
+			}
+			for _, sm := range machines {
+				add(mstat, sm, []Dimension{
+					Dimension{
+						Name:  "StateMachineArn",
+						Value: sm.Arn,
+					},
+				})
+			}
+		case athenaNamespace:
+			dimensionSets, err := DiscoverDimensionSets(cloudwatchSvc, mstat.Namespace, mstat.Name, athenaDimensionKeys)
+			if err != nil {
+				return metrics, err
+			}
+			for _, wg := range workgroups {
+				for _, dims := range dimensionSets {
+					for _, d := range dims {
+						if d.Name == "WorkGroup" && d.Value == wg.Name {
+							add(mstat, wg, dims)
+						}
+					}
+				}
+			}
+		default:
+			log.Printf("Namespace %s is not supported by DataPipeline, skipping metric %s", mstat.Namespace, mstat.Name)
+		}
+	}
+	return metrics, nil
+}
+
+func getGlueDpuSeconds(runs []GlueJobRun) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, r := range runs {
+		properties := GetDataPipelineMetricProperties(r.Job)
+		if len(r.WorkerType) >= 2 {
+			properties["worker_type"] = r.WorkerType
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   properties,
+			Timestamp:    metrics3.AnodotTimestamp{r.Completed},
+			Measurements: map[string]float64{glueDpuSecondsMeasurment: r.DPUSeconds},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func getGlueDpuSecondsMetrics(session *session.Session, jobs []PipelineResource) ([]metrics3.AnodotMetrics30, error) {
+	region := *session.Config.Region
+	now := time.Now()
+
+	since, ok, err := GetCheckpoint(region, glueJobRunsCheckpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		since = now.Add(-offset)
+	}
+
+	runs, err := GetGlueJobRuns(session, jobs, since, now)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Found %d Glue job runs completed since %s", len(runs), since.Format(time.RFC3339))
+
+	checkpoints.Append(region, glueJobRunsCheckpoint, now)
+	return getGlueDpuSeconds(runs), nil
+}
+
+func GetDataPipelineMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	region := *ses.Config.Region
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	caller, err := getCallerArn(ses)
+	if err != nil {
+		log.Printf("Cloud not get caller identity: %v", err)
+		return anodotMetrics, err
+	}
+
+	machines, err := GetStateMachines(ses, resource)
+	if err != nil {
+		log.Printf("Cloud not list state machines: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d state machines", len(machines))
+
+	jobs, err := GetGlueJobs(ses, resource, caller)
+	if err != nil {
+		log.Printf("Cloud not list Glue jobs: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Glue jobs", len(jobs))
+
+	workgroups, err := GetAthenaWorkgroups(ses, resource, caller)
+	if err != nil {
+		log.Printf("Cloud not list Athena workgroups: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Athena workgroups", len(workgroups))
+
+	metrics, err := GetDataPipelineCloudwatchMetrics(cloudwatchSvc, resource, region, machines, workgroups)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during DataPipeline metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					r := m.Resource.(PipelineResource)
+					properties := GetDataPipelineMetricProperties(r)
+					for _, d := range m.Dimensions {
+						switch d.Name {
+						case "QueryState":
+							properties["query_state"] = d.Value
+						case "QueryType":
+							properties["query_type"] = d.Value
+						}
+					}
+					anodot_pipeline_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, properties)
+					anodotMetrics = append(anodotMetrics, anodot_pipeline_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "GlueDPUSeconds" || cm == glueDpuSecondsMeasurment {
+				log.Printf("Processing DataPipeline custom metric GlueDPUSeconds\n")
+				dpuMetrics, err := getGlueDpuSecondsMetrics(ses, jobs)
+				if err != nil {
+					log.Printf("Cloud not get Glue job runs: %v", err)
+					return anodotMetrics, err
+				}
+				anodotMetrics = append(anodotMetrics, dpuMetrics...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
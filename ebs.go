@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"strconv"
 	"time"
@@ -20,6 +19,9 @@ type EBS struct {
 	State         string
 	AZ            string
 	IOPS          int64
+	Throughput    int64 // MiB/s, provisioned for gp3 only
+	InstanceId    string
+	Device        string
 	Region        string
 	Size          int64
 	DimensionTags []string
@@ -103,12 +105,24 @@ func GetEBSVolumes(session *session.Session, customtags []Tag, resource *Monitor
 			Region:        *region,
 			IOPS:          0,
 			State:         *v.State,
+			Tags:          v.Tags,
+			InstanceId:    "None",
+			Device:        "None",
 			DimensionTags: resource.DimensionTags,
 		}
 
 		if v.Iops != nil {
 			ebs.IOPS = *v.Iops
 		}
+		// Only gp3 has provisioned throughput, io2 throughput depends on IOPS and I/O size and is not reported
+		if v.Throughput != nil {
+			ebs.Throughput = *v.Throughput
+		}
+		// Multi-Attach io1/io2 volumes are reported with the first attachment
+		if len(v.Attachments) > 0 {
+			ebs.InstanceId = aws.StringValue(v.Attachments[0].InstanceId)
+			ebs.Device = aws.StringValue(v.Attachments[0].Device)
+		}
 
 		ebslist = append(ebslist, ebs)
 	}
@@ -123,6 +137,8 @@ func GetEBSDimensions(resource *MonitoredResource) []string {
 		"state",
 		"availability_zone",
 		"iops",
+		"instance_id",
+		"device",
 		"region",
 		"anodot-collector",
 	}
@@ -137,6 +153,11 @@ func GetEBSCustomMetrics() []CustomMetricDefinition {
 			Alias:      "Size",
 			TargetType: "sum",
 		},
+		CustomMetricDefinition{
+			Name:       "throughput",
+			Alias:      "Throughput",
+			TargetType: "sum",
+		},
 	}
 }
 
@@ -148,13 +169,14 @@ func GetEBSMetricProperties(ebs EBS) map[string]string {
 		"state":             ebs.State,
 		"availability_zone": ebs.AZ,
 		"iops":              strconv.Itoa(int(ebs.IOPS)),
+		"instance_id":       ebs.InstanceId,
+		"device":            ebs.Device,
 		"region":            ebs.Region,
 		"anodot-collector":  "aws",
 	}
 
 	for _, v := range ebs.Tags {
 		for _, dt := range ebs.DimensionTags {
-			fmt.Println(*v.Key)
 			if *v.Key == dt {
 				if len(*v.Key) > 50 || len(*v.Value) < 2 {
 					continue
@@ -189,6 +211,45 @@ func getEBSSizeMetric(ebs []EBS) []metrics3.AnodotMetrics30 {
 	return metrics
 }
 
+func getEBSThroughputMetric(ebs []EBS) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, e := range ebs {
+		if e.Throughput == 0 {
+			continue
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetEBSMetricProperties(e),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"throughput": float64(e.Throughput)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetEBSCloudwatchMetrics(resource *MonitoredResource, ebss []EBS) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, e := range ebss {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "VolumeId",
+					Value: e.Id,
+				},
+			}
+			m.Resource = e
+			mstatCopy := mstat
+			mstatCopy.Id = "ebs" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+
+	return metrics, nil
+}
+
 func GetEBSMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
 	metrics := make([]metrics3.AnodotMetrics30, 0)
 	ebss, err := GetEBSVolumes(session, resource.Tags, resource)
@@ -198,12 +259,47 @@ func GetEBSMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWa
 		return metrics, err
 	}
 	log.Printf("Got %d EBS volumes to process", len(ebss))
+
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+	cmetrics, err := GetEBSCloudwatchMetrics(resource, ebss)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return metrics, err
+	}
+
+	if len(cmetrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(cmetrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during EBS metrics processing: %v", err)
+			return metrics, err
+		}
+
+		for _, m := range cmetrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					e := m.Resource.(EBS)
+					metrics = append(
+						metrics,
+						GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetEBSMetricProperties(e))...,
+					)
+				}
+			}
+		}
+	}
+
 	if len(resource.CustomMetrics) > 0 {
 		for _, cm := range resource.CustomMetrics {
 			if cm == "Size" {
 				log.Printf("Processing EBS custom metric Size\n")
 				metrics = append(metrics, getEBSSizeMetric(ebss)...)
 			}
+			if cm == "Throughput" {
+				log.Printf("Processing EBS custom metric Throughput\n")
+				metrics = append(metrics, getEBSThroughputMetric(ebss)...)
+			}
 		}
 	}
 	return metrics, nil
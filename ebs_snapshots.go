@@ -0,0 +1,197 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type Snapshot struct {
+	Id            string
+	VolumeId      string
+	Size          int64
+	State         string
+	Encrypted     bool
+	StartTime     time.Time
+	AmiReferenced bool
+	Tags          []*ec2.Tag
+	Region        string
+	DimensionTags []string
+}
+
+// GetAmiSnapshotIds returns set of snapshot ids referenced by AMIs owned by the account
+func GetAmiSnapshotIds(ec2svc *ec2.EC2) (map[string]bool, error) {
+	snapshotIds := make(map[string]bool)
+	output, err := ec2svc.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String("self")},
+	})
+	if err != nil {
+		return snapshotIds, err
+	}
+
+	for _, image := range output.Images {
+		for _, bdm := range image.BlockDeviceMappings {
+			if bdm.Ebs != nil && bdm.Ebs.SnapshotId != nil {
+				snapshotIds[*bdm.Ebs.SnapshotId] = true
+			}
+		}
+	}
+	return snapshotIds, nil
+}
+
+func GetEBSSnapshots(session *session.Session, resource *MonitoredResource) ([]Snapshot, error) {
+	snapshots := make([]Snapshot, 0)
+	region := session.Config.Region
+	ec2svc := ec2.New(session)
+	rawsnapshots := make([]*ec2.Snapshot, 0)
+
+	input := &ec2.DescribeSnapshotsInput{
+		OwnerIds:   []*string{aws.String("self")},
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		output, err := ec2svc.DescribeSnapshots(input)
+		if err != nil {
+			return snapshots, err
+		}
+		rawsnapshots = append(rawsnapshots, output.Snapshots...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	amiSnapshots, err := GetAmiSnapshotIds(ec2svc)
+	if err != nil {
+		return snapshots, err
+	}
+
+	for _, s := range rawsnapshots {
+		snapshots = append(snapshots, Snapshot{
+			Id:            *s.SnapshotId,
+			VolumeId:      aws.StringValue(s.VolumeId),
+			Size:          aws.Int64Value(s.VolumeSize),
+			State:         aws.StringValue(s.State),
+			Encrypted:     aws.BoolValue(s.Encrypted),
+			StartTime:     aws.TimeValue(s.StartTime),
+			AmiReferenced: amiSnapshots[*s.SnapshotId],
+			Tags:          s.Tags,
+			Region:        *region,
+			DimensionTags: resource.DimensionTags,
+		})
+	}
+	return snapshots, nil
+}
+
+func GetEBSSnapshotsDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"snapshot_id",
+		"volume_id",
+		"state",
+		"encrypted",
+		"ami_referenced",
+		"age_bucket",
+		"region",
+		"anodot-collector",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetEBSSnapshotsCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "snapshot_size",
+			Alias:      "Size",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "snapshot_count",
+			Alias:      "Count",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetEBSSnapshotMetricProperties(s Snapshot) map[string]string {
+	properties := map[string]string{
+		"service":          "ebs_snapshot",
+		"snapshot_id":      s.Id,
+		"volume_id":        s.VolumeId,
+		"state":            s.State,
+		"encrypted":        strconv.FormatBool(s.Encrypted),
+		"ami_referenced":   strconv.FormatBool(s.AmiReferenced),
+		"age_bucket":       ageBucket(s.StartTime),
+		"region":           s.Region,
+		"anodot-collector": "aws",
+	}
+
+	for _, v := range s.Tags {
+		for _, dt := range s.DimensionTags {
+			if *v.Key == dt {
+				if len(*v.Key) > 50 || len(*v.Value) < 2 {
+					continue
+				}
+				if len(properties) == 17 {
+					break
+				}
+				properties[escape(*v.Key)] = escape(*v.Value)
+			}
+		}
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getEBSSnapshotMetric(snapshots []Snapshot, what string) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, s := range snapshots {
+		// snapshot_count is reported as 1 per snapshot, Anodot sums it up by dimensions
+		value := float64(1)
+		if what == "snapshot_size" {
+			value = float64(s.Size)
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetEBSSnapshotMetricProperties(s),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{what: value},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetEBSSnapshotsMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	snapshots, err := GetEBSSnapshots(session, resource)
+	if err != nil {
+		log.Printf("Cloud not describe EBS snapshots %v", err)
+		return metrics, err
+	}
+	log.Printf("Got %d EBS snapshots to process", len(snapshots))
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "Size" {
+				log.Printf("Processing EBSSnapshots custom metric Size\n")
+				metrics = append(metrics, getEBSSnapshotMetric(snapshots, "snapshot_size")...)
+			}
+			if cm == "Count" {
+				log.Printf("Processing EBSSnapshots custom metric Count\n")
+				metrics = append(metrics, getEBSSnapshotMetric(snapshots, "snapshot_count")...)
+			}
+		}
+	}
+	return metrics, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"regexp"
 	"strconv"
 	"time"
 
@@ -13,6 +14,28 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
+// Instances in these states are reported, terminated ones are not billed anymore
+var reportedInstanceStates = []string{"pending", "running", "shutting-down", "stopping", "stopped"}
+
+// Time of the last state change is known only from the reason, e.g. "User initiated (2021-07-20 10:11:12 GMT)"
+var stateTransitionTime = regexp.MustCompile(`\((\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) GMT\)`)
+
+const stateTransitionTimeFormat = "2006-01-02 15:04:05"
+
+// DescribeInstanceTypes accepts at most 100 instance types per call
+const instanceTypesBatch = 100
+
+// Instance attributes reported as dimensions when DetailedAttributes is enabled
+var detailedInstanceDimensions = []string{
+	"platform",
+	"tenancy",
+	"architecture",
+	"ebs_optimized",
+	"hypervisor",
+	"launch_age",
+	"linked_request",
+}
+
 type Instance struct {
 	InstanceId         string
 	InstanceType       string
@@ -27,6 +50,18 @@ type Instance struct {
 	ThreadsPerCore     int64
 	Region             string
 	Lifecycle          string
+	AsgName            string
+	LaunchTime         time.Time
+	StateTransition    time.Time
+	Platform           string
+	Tenancy            string
+	Architecture       string
+	EbsOptimized       bool
+	Hypervisor         string
+	LinkedRequest      string
+	MemoryMiB          int64
+	HourlyPrice        float64
+	Detailed           bool
 	DimensionTags      []string
 }
 
@@ -48,10 +83,8 @@ type EC2Fetcher struct {
 func CreateEC2Fetcher(session *session.Session) EC2Fetcher {
 	region := session.Config.Region
 	f := &ec2.Filter{
-		Name: aws.String("instance-state-code"),
-		Values: []*string{
-			aws.String("16"),
-		},
+		Name:   aws.String("instance-state-name"),
+		Values: aws.StringSlice(reportedInstanceStates),
 	}
 	ec2s := ec2.New(session)
 	return EC2Fetcher{
@@ -92,11 +125,6 @@ func (ec2fetcher *EC2Fetcher) GetInstances(resource *MonitoredResource) (ListIns
 			fmt.Println("Error", err)
 			return nil, err
 		}
-
-		if len(result.Reservations) == 0 {
-			fmt.Println("Not found any instances")
-			return nil, fmt.Errorf("Error: Can not find any instances with this input params")
-		}
 		reservation = append(reservation, result.Reservations...)
 		nexttoken = result.NextToken
 		if nexttoken == nil {
@@ -110,10 +138,6 @@ func (ec2fetcher *EC2Fetcher) GetInstances(resource *MonitoredResource) (ListIns
 
 	for _, i := range ec2list {
 		var vpcId string
-		if *i.State.Code != 16 {
-			fmt.Printf("Instance %s in not running state: %s \n", *i.InstanceId, *i.State.Name)
-			continue
-		}
 		lifecycle := "normal"
 		if i.InstanceLifecycle != nil {
 			lifecycle = *i.InstanceLifecycle
@@ -124,6 +148,35 @@ func (ec2fetcher *EC2Fetcher) GetInstances(resource *MonitoredResource) (ListIns
 		} else {
 			vpcId = *i.VpcId
 		}
+
+		asgName := "None"
+		for _, t := range i.Tags {
+			if *t.Key == asgNameTag {
+				asgName = *t.Value
+			}
+		}
+
+		// Capacity reservation or spot request the instance was launched for
+		linkedRequest := "None"
+		if i.CapacityReservationId != nil {
+			linkedRequest = *i.CapacityReservationId
+		} else if i.SpotInstanceRequestId != nil {
+			linkedRequest = *i.SpotInstanceRequestId
+		}
+
+		// Platform is named as in price list and spot price history, Platform field is set to "windows" for Windows only
+		platform := aws.StringValue(i.PlatformDetails)
+		if platform == "" {
+			platform = "Linux/UNIX"
+			if aws.StringValue(i.Platform) == "windows" {
+				platform = "Windows"
+			}
+		}
+
+		var transition time.Time
+		if m := stateTransitionTime.FindStringSubmatch(aws.StringValue(i.StateTransitionReason)); m != nil {
+			transition, _ = time.Parse(stateTransitionTimeFormat, m[1])
+		}
 		li = append(li, Instance{
 			CoreCount:          *i.CpuOptions.CoreCount,
 			ThreadsPerCore:     *i.CpuOptions.ThreadsPerCore,
@@ -138,6 +191,16 @@ func (ec2fetcher *EC2Fetcher) GetInstances(resource *MonitoredResource) (ListIns
 			VirtualizationType: *i.VirtualizationType,
 			Region:             ec2fetcher.region,
 			Lifecycle:          lifecycle,
+			AsgName:            asgName,
+			LaunchTime:         aws.TimeValue(i.LaunchTime),
+			StateTransition:    transition,
+			Platform:           platform,
+			Tenancy:            aws.StringValue(i.Placement.Tenancy),
+			Architecture:       aws.StringValue(i.Architecture),
+			EbsOptimized:       aws.BoolValue(i.EbsOptimized),
+			Hypervisor:         aws.StringValue(i.Hypervisor),
+			LinkedRequest:      linkedRequest,
+			Detailed:           resource.DetailedAttributes,
 			DimensionTags:      resource.DimensionTags,
 		})
 
@@ -145,6 +208,70 @@ func (ec2fetcher *EC2Fetcher) GetInstances(resource *MonitoredResource) (ListIns
 	return ListInstances(li), nil
 }
 
+// InstanceHours returns how long the instance was running within the window ending at end.
+// LaunchTime is the time of the last start, stopped instances ran until their last state transition
+func (ins Instance) InstanceHours(end time.Time, window time.Duration) float64 {
+	start := end.Add(-window)
+	if ins.LaunchTime.After(start) {
+		start = ins.LaunchTime
+	}
+
+	switch ins.State {
+	case "running":
+	case "stopping", "stopped", "shutting-down":
+		if ins.StateTransition.IsZero() {
+			return 0
+		}
+		if ins.StateTransition.Before(end) {
+			end = ins.StateTransition
+		}
+	default:
+		// pending instances are not billed yet
+		return 0
+	}
+
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start).Hours()
+}
+
+// SetMemory fills MemoryMiB of instances from DescribeInstanceTypes
+func (ec2fetcher *EC2Fetcher) SetMemory(instances ListInstances) error {
+	types := make([]string, 0)
+	for _, i := range instances {
+		types = append(types, i.InstanceType)
+	}
+	types = removeDuplicates(types)
+
+	memory := make(map[string]int64)
+	for start := 0; start < len(types); start += instanceTypesBatch {
+		end := start + instanceTypesBatch
+		if end > len(types) {
+			end = len(types)
+		}
+		input := &ec2.DescribeInstanceTypesInput{
+			InstanceTypes: aws.StringSlice(types[start:end]),
+		}
+		err := ec2fetcher.instanceService.DescribeInstanceTypesPages(input, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+			for _, t := range page.InstanceTypes {
+				if t.MemoryInfo != nil {
+					memory[*t.InstanceType] = aws.Int64Value(t.MemoryInfo.SizeInMiB)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range instances {
+		instances[i].MemoryMiB = memory[instances[i].InstanceType]
+	}
+	return nil
+}
+
 func getInput(fl Filters, nexttoken *string) *ec2.DescribeInstancesInput {
 	maxresult := int64(1000)
 	if len(fl) < 0 {
@@ -174,8 +301,12 @@ func GetEc2Dimensions(resource *MonitoredResource) []string {
 		"threads_per_core",
 		"region",
 		"lifecycle",
+		"asg_name",
 		"anodot-collector",
 	}
+	if resource.DetailedAttributes {
+		dims = append(dims, detailedInstanceDimensions...)
+	}
 	return append(dims, resource.DimensionTags...)
 }
 
@@ -188,7 +319,27 @@ func GetEc2CustomMetrics() []CustomMetricDefinition {
 		},
 		{
 			Name:       "vcpu_count",
-			Alias:      "CoreCount",
+			Alias:      "VCpuCount",
+			TargetType: "sum",
+		},
+		{
+			Name:       "memory_gib",
+			Alias:      "MemoryGiB",
+			TargetType: "sum",
+		},
+		{
+			Name:       "instance_hours",
+			Alias:      "InstanceHours",
+			TargetType: "sum",
+		},
+		{
+			Name:       "hourly_price",
+			Alias:      "HourlyPrice",
+			TargetType: "average",
+		},
+		{
+			Name:       "estimated_cost",
+			Alias:      "EstimatedCost",
 			TargetType: "sum",
 		},
 	}
@@ -208,6 +359,7 @@ func GetEc2MetricProperties(ins Instance) map[string]string {
 		"threads_per_core":    strconv.Itoa(int(ins.ThreadsPerCore)),
 		"region":              ins.Region,
 		"lifecycle":           ins.Lifecycle,
+		"asg_name":            ins.AsgName,
 		"anodot-collector":    "aws",
 	}
 
@@ -225,6 +377,29 @@ func GetEc2MetricProperties(ins Instance) map[string]string {
 		}
 	}
 
+	// Detailed attributes are added after tags, so they do not take place of tag dimensions,
+	// and only while there is room left within the dimensions limit
+	if ins.Detailed {
+		detailed := [][2]string{
+			{"platform", ins.Platform},
+			{"tenancy", ins.Tenancy},
+			{"architecture", ins.Architecture},
+			{"launch_age", ageBucket(ins.LaunchTime)},
+			{"linked_request", ins.LinkedRequest},
+			{"ebs_optimized", strconv.FormatBool(ins.EbsOptimized)},
+			{"hypervisor", ins.Hypervisor},
+		}
+		for _, d := range detailed {
+			if len(properties) == 17 {
+				break
+			}
+			if len(d[1]) > 50 || len(d[1]) < 2 {
+				continue
+			}
+			properties[d[0]] = d[1]
+		}
+	}
+
 	for k, v := range properties {
 		if len(v) > 50 || len(v) < 2 {
 			delete(properties, k)
@@ -264,11 +439,70 @@ func getVirtualCpuCountMetric30(ins []Instance) []metrics3.AnodotMetrics30 {
 	return metrics
 }
 
+func getMemoryMetric30(ins []Instance) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+
+	for _, i := range ins {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetEc2MetricProperties(i),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"memory_gib": float64(i.MemoryMiB) / 1024},
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+func getInstanceHoursMetric30(ins []Instance) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	now := time.Now()
+
+	for _, i := range ins {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetEc2MetricProperties(i),
+			Timestamp:    metrics3.AnodotTimestamp{now},
+			Measurements: map[string]float64{"instance_hours": i.InstanceHours(now, offset)},
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// getPriceMetric30 skips instances with unknown price (not in price table or spot price history)
+func getPriceMetric30(ins []Instance, what string) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	now := time.Now()
+
+	for _, i := range ins {
+		if i.HourlyPrice == 0 {
+			continue
+		}
+		value := i.HourlyPrice
+		if what == "estimated_cost" {
+			value = i.HourlyPrice * i.InstanceHours(now, offset)
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetEc2MetricProperties(i),
+			Timestamp:    metrics3.AnodotTimestamp{now},
+			Measurements: map[string]float64{what: value},
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
 func GetEc2CloudwatchMetrics(resource *MonitoredResource, instances []Instance) ([]MetricToFetch, error) {
 	metrics := make([]MetricToFetch, 0)
 
 	for _, mstat := range resource.Metrics {
 		for _, i := range instances {
+			// Stopped instances do not report to CloudWatch
+			if i.State == "stopped" {
+				continue
+			}
 			m := MetricToFetch{}
 			m.Dimensions = []Dimension{
 				Dimension{
@@ -329,6 +563,15 @@ func GetEc2Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWa
 		}
 	}
 
+	pricesSet := false
+	setPrices := func() error {
+		if pricesSet {
+			return nil
+		}
+		pricesSet = true
+		return instanceFetcher.SetPrices(instances, resource.PriceTable)
+	}
+
 	if len(resource.CustomMetrics) > 0 {
 		for _, cm := range resource.CustomMetrics {
 			if cm == "CoreCount" {
@@ -339,6 +582,32 @@ func GetEc2Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWa
 				log.Printf("Processing EC2 custom metric VCpuCount\n")
 				metrics = append(metrics, getVirtualCpuCountMetric30(instances)...)
 			}
+			if cm == "MemoryGiB" {
+				log.Printf("Processing EC2 custom metric MemoryGiB\n")
+				err := instanceFetcher.SetMemory(instances)
+				if err != nil {
+					log.Printf("Could not describe EC2 instance types %v", err)
+					return metrics, err
+				}
+				metrics = append(metrics, getMemoryMetric30(instances)...)
+			}
+			if cm == "InstanceHours" {
+				log.Printf("Processing EC2 custom metric InstanceHours\n")
+				metrics = append(metrics, getInstanceHoursMetric30(instances)...)
+			}
+			if cm == "HourlyPrice" || cm == "EstimatedCost" {
+				log.Printf("Processing EC2 custom metric %s\n", cm)
+				err := setPrices()
+				if err != nil {
+					log.Printf("Could not get EC2 prices %v", err)
+					return metrics, err
+				}
+				if cm == "HourlyPrice" {
+					metrics = append(metrics, getPriceMetric30(instances, "hourly_price")...)
+				} else {
+					metrics = append(metrics, getPriceMetric30(instances, "estimated_cost")...)
+				}
+			}
 		}
 	}
 
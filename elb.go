@@ -3,8 +3,11 @@ package main
 import (
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/elb"
@@ -13,6 +16,54 @@ import (
 
 var pageSize int64 = 400
 
+// DescribeTags accepts at most 20 load balancers per call
+const elbTagsBatchSize = 20
+
+// CloudWatch namespace by load balancer type
+var elbNamespaces = map[string]string{
+	"classic":     "AWS/ELB",
+	"application": "AWS/ApplicationELB",
+	"network":     "AWS/NetworkELB",
+}
+
+// CloudWatch metric names differ between load balancer types.
+// Config uses Classic ELB names, they are translated per type before fetching.
+// Network load balancers have no requests, their flows are fetched as a separate NewFlowCount metric.
+var elbMetricNames = map[string]map[string]string{
+	"classic": {
+		"RequestCount":            "RequestCount",
+		"EstimatedProcessedBytes": "EstimatedProcessedBytes",
+		"HealthyHostCount":        "HealthyHostCount",
+		"TargetResponseTime":      "Latency",
+	},
+	"application": {
+		"RequestCount":            "RequestCount",
+		"EstimatedProcessedBytes": "ProcessedBytes",
+		"ConsumedLCUs":            "ConsumedLCUs",
+		"HealthyHostCount":        "HealthyHostCount",
+		"TargetResponseTime":      "TargetResponseTime",
+	},
+	"network": {
+		"NewFlowCount":            "NewFlowCount",
+		"EstimatedProcessedBytes": "ProcessedBytes",
+		"ConsumedLCUs":            "ConsumedLCUs",
+		"HealthyHostCount":        "HealthyHostCount",
+	},
+}
+
+// Application and network load balancers publish these metrics per target group
+var elbTargetGroupMetrics = map[string]bool{
+	"HealthyHostCount":   true,
+	"TargetResponseTime": true,
+}
+
+type TargetGroup struct {
+	Name string
+	// CloudWatch TargetGroup dimension, targetgroup/<name>/<id>
+	ArnSuffix        string
+	LoadBalancerArns []string
+}
+
 type LoadBalancerTag struct {
 	Key   string
 	Value string
@@ -20,12 +71,33 @@ type LoadBalancerTag struct {
 
 type LoadBalancer struct {
 	Name          string
+	Arn           string
 	Az            string
+	AzCount       int
 	VPCId         string
 	Type          string
 	Tags          []LoadBalancerTag
 	Region        string
 	DimensionTags []string
+	TargetGroups  []TargetGroup
+	// Set for series of a single target group
+	TargetGroup string
+}
+
+// loadBalancerArnSuffix returns app/<name>/<id> part of load balancer arn used by CloudWatch
+func loadBalancerArnSuffix(arn string) string {
+	if i := strings.Index(arn, ":loadbalancer/"); i >= 0 {
+		return arn[i+len(":loadbalancer/"):]
+	}
+	return arn
+}
+
+// targetGroupArnSuffix returns targetgroup/<name>/<id> part of target group arn used by CloudWatch
+func targetGroupArnSuffix(arn string) string {
+	if i := strings.Index(arn, ":targetgroup/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
 }
 
 func GetLoadBalancers(session *session.Session, resource *MonitoredResource) ([]LoadBalancer, error) {
@@ -40,8 +112,20 @@ func GetLoadBalancers(session *session.Session, resource *MonitoredResource) ([]
 		return balancers, err
 	}
 
+	targetGroups, err := GetTargetGroups(session)
+	if err != nil {
+		return balancers, err
+	}
+
 	for _, nab := range netandapp {
 		nab.DimensionTags = resource.DimensionTags
+		for _, tg := range targetGroups {
+			for _, arn := range tg.LoadBalancerArns {
+				if arn == nab.Arn {
+					nab.TargetGroups = append(nab.TargetGroups, tg)
+				}
+			}
+		}
 		balancers = append(balancers, nab)
 	}
 
@@ -62,34 +146,76 @@ func GetAppAndNetworkBalancers(session *session.Session) ([]LoadBalancer, error)
 		PageSize: &pageSize,
 	}
 
-	result, err := elbSvc.DescribeLoadBalancers(input)
+	err := elbSvc.DescribeLoadBalancersPages(input, func(page *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range page.LoadBalancers {
+			balancer := LoadBalancer{
+				Name:    *lb.LoadBalancerName,
+				Arn:     *lb.LoadBalancerArn,
+				VPCId:   aws.StringValue(lb.VpcId),
+				Type:    *lb.Type,
+				AzCount: len(lb.AvailabilityZones),
+				Region:  *region,
+			}
+			if len(lb.AvailabilityZones) > 0 {
+				balancer.Az = aws.StringValue(lb.AvailabilityZones[0].ZoneName)
+			}
+			balancers = append(balancers, balancer)
+		}
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	for _, lb := range result.LoadBalancers {
+	for start := 0; start < len(balancers); start += elbTagsBatchSize {
+		end := start + elbTagsBatchSize
+		if end > len(balancers) {
+			end = len(balancers)
+		}
+		arns := make([]*string, 0)
+		for _, lb := range balancers[start:end] {
+			arns = append(arns, aws.String(lb.Arn))
+		}
+
 		desctagsoutput, err := elbSvc.DescribeTags(&elbv2.DescribeTagsInput{
-			ResourceArns: []*string{lb.LoadBalancerArn},
+			ResourceArns: arns,
 		})
-
 		if err != nil {
-			log.Printf("Could not get tags for %s", *lb.DNSName)
+			log.Printf("Could not get tags for load balancers: %v", err)
 			continue
 		}
 
-		balancers = append(balancers, LoadBalancer{
-			Name:   *lb.LoadBalancerName,
-			Az:     *lb.AvailabilityZones[0].ZoneName,
-			VPCId:  *lb.VpcId,
-			Type:   *lb.Type,
-			Tags:   convertTags(desctagsoutput.TagDescriptions[0].Tags),
-			Region: *region,
-		})
-
+		tags := make(map[string][]LoadBalancerTag)
+		for _, td := range desctagsoutput.TagDescriptions {
+			tags[*td.ResourceArn] = convertTags(td.Tags)
+		}
+		for i := start; i < end; i++ {
+			balancers[i].Tags = tags[balancers[i].Arn]
+		}
 	}
 	return balancers, nil
 }
 
+func GetTargetGroups(session *session.Session) ([]TargetGroup, error) {
+	elbSvc := elbv2.New(session)
+	targetGroups := make([]TargetGroup, 0)
+
+	err := elbSvc.DescribeTargetGroupsPages(&elbv2.DescribeTargetGroupsInput{}, func(page *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+		for _, tg := range page.TargetGroups {
+			targetGroups = append(targetGroups, TargetGroup{
+				Name:             *tg.TargetGroupName,
+				ArnSuffix:        targetGroupArnSuffix(*tg.TargetGroupArn),
+				LoadBalancerArns: aws.StringValueSlice(tg.LoadBalancerArns),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return targetGroups, err
+	}
+	return targetGroups, nil
+}
+
 func GetClassicBalancers(session *session.Session) ([]LoadBalancer, error) {
 	elbSvc := elb.New(session)
 	region := session.Config.Region
@@ -99,30 +225,51 @@ func GetClassicBalancers(session *session.Session) ([]LoadBalancer, error) {
 		PageSize: &pageSize,
 	}
 
-	result, err := elbSvc.DescribeLoadBalancers(input)
+	err := elbSvc.DescribeLoadBalancersPages(input, func(page *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range page.LoadBalancerDescriptions {
+			balancer := LoadBalancer{
+				Name:    *lb.LoadBalancerName,
+				VPCId:   aws.StringValue(lb.VPCId),
+				Type:    "classic",
+				AzCount: len(lb.AvailabilityZones),
+				Region:  *region,
+			}
+			if len(lb.AvailabilityZones) > 0 {
+				balancer.Az = *lb.AvailabilityZones[0]
+			}
+			balancers = append(balancers, balancer)
+		}
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	for _, lb := range result.LoadBalancerDescriptions {
+	for start := 0; start < len(balancers); start += elbTagsBatchSize {
+		end := start + elbTagsBatchSize
+		if end > len(balancers) {
+			end = len(balancers)
+		}
+		names := make([]*string, 0)
+		for _, lb := range balancers[start:end] {
+			names = append(names, aws.String(lb.Name))
+		}
+
 		desctagsoutput, err := elbSvc.DescribeTags(&elb.DescribeTagsInput{
-			LoadBalancerNames: []*string{lb.LoadBalancerName},
+			LoadBalancerNames: names,
 		})
-
 		if err != nil {
-			log.Printf("Could not get tags for %s", *lb.DNSName)
+			log.Printf("Could not get tags for classic load balancers: %v", err)
 			continue
 		}
 
-		balancers = append(balancers, LoadBalancer{
-			Name:   *lb.LoadBalancerName,
-			Az:     *lb.AvailabilityZones[0],
-			VPCId:  *lb.VPCId,
-			Type:   "classic",
-			Tags:   convertTags(desctagsoutput.TagDescriptions[0].Tags),
-			Region: *region,
-		})
-
+		tags := make(map[string][]LoadBalancerTag)
+		for _, td := range desctagsoutput.TagDescriptions {
+			tags[*td.LoadBalancerName] = convertTags(td.Tags)
+		}
+		for i := start; i < end; i++ {
+			balancers[i].Tags = tags[balancers[i].Name]
+		}
 	}
 	return balancers, nil
 }
@@ -136,10 +283,21 @@ func GetELBDimensions(resource *MonitoredResource) []string {
 		"region",
 		"anodot-collector",
 		"type",
+		"target_group",
 	}
 	return removeDuplicates(append(dims, resource.DimensionTags...))
 }
 
+func GetELBCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "az_count",
+			Alias:      "AzCount",
+			TargetType: "average",
+		},
+	}
+}
+
 func GetELBMetricProperties(elb LoadBalancer) map[string]string {
 	properties := map[string]string{
 		"service":          "elb",
@@ -149,6 +307,7 @@ func GetELBMetricProperties(elb LoadBalancer) map[string]string {
 		"region":           elb.Region,
 		"anodot-collector": "aws",
 		"type":             elb.Type,
+		"target_group":     escape(elb.TargetGroup),
 	}
 
 	for _, v := range elb.Tags {
@@ -178,23 +337,75 @@ func GetELBCloudwatchMetrics(resource *MonitoredResource, elbs []LoadBalancer) (
 
 	for _, mstat := range resource.Metrics {
 		for _, elb := range elbs {
-			m := MetricToFetch{}
-			m.Dimensions = []Dimension{
-				Dimension{
-					Name:  "LoadBalancerName",
-					Value: elb.Name,
-				},
+			name, ok := elbMetricNames[elb.Type][mstat.Name]
+			if !ok {
+				continue
 			}
-			m.Resource = elb
 			mstatCopy := mstat
-			mstatCopy.Id = "elb" + strconv.Itoa(len(metrics))
-			m.MStat = mstatCopy
-			metrics = append(metrics, m)
+			mstatCopy.Name = name
+			mstatCopy.Namespace = elbNamespaces[elb.Type]
+
+			if elb.Type == "classic" {
+				m := MetricToFetch{}
+				m.Dimensions = []Dimension{
+					Dimension{
+						Name:  "LoadBalancerName",
+						Value: elb.Name,
+					},
+				}
+				m.Resource = elb
+				mstatCopy.Id = "elb" + strconv.Itoa(len(metrics))
+				m.MStat = mstatCopy
+				metrics = append(metrics, m)
+				continue
+			}
+
+			lbDimension := Dimension{
+				Name:  "LoadBalancer",
+				Value: loadBalancerArnSuffix(elb.Arn),
+			}
+			if !elbTargetGroupMetrics[mstat.Name] {
+				m := MetricToFetch{}
+				m.Dimensions = []Dimension{lbDimension}
+				m.Resource = elb
+				mstatCopy.Id = "elb" + strconv.Itoa(len(metrics))
+				m.MStat = mstatCopy
+				metrics = append(metrics, m)
+				continue
+			}
+
+			for _, tg := range elb.TargetGroups {
+				m := MetricToFetch{}
+				m.Dimensions = []Dimension{
+					Dimension{
+						Name:  "TargetGroup",
+						Value: tg.ArnSuffix,
+					},
+					lbDimension,
+				}
+				tgElb := elb
+				tgElb.TargetGroup = tg.Name
+				m.Resource = tgElb
+				mstatCopy.Id = "elb" + strconv.Itoa(len(metrics))
+				m.MStat = mstatCopy
+				metrics = append(metrics, m)
+			}
 		}
 	}
 	return metrics, nil
 }
 
+// elbMeasurementName maps a type specific CloudWatch metric name back to the configured one,
+// so all load balancer types end up in the same schema measurement.
+func elbMeasurementName(lbType, name string) string {
+	for configured, cwname := range elbMetricNames[lbType] {
+		if cwname == name {
+			return configured
+		}
+	}
+	return name
+}
+
 func convertTags(tags interface{}) []LoadBalancerTag {
 	blancertags := make([]LoadBalancerTag, 0)
 	if tags_, ok := tags.([]*elbv2.Tag); ok {
@@ -211,6 +422,19 @@ func convertTags(tags interface{}) []LoadBalancerTag {
 	return blancertags
 }
 
+func getELBAzCountMetric(elbs []LoadBalancer) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, e := range elbs {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetELBMetricProperties(e),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"az_count": float64(e.AzCount)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
 func GetELBMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
 	cloudWatchFetcher := CloudWatchFetcher{
 		cloudwatchSvc: cloudwatchSvc,
@@ -242,10 +466,20 @@ func GetELBMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWa
 			if *mr.Id == m.MStat.Id {
 				e := m.Resource.(LoadBalancer)
 				//log.Printf("Fetching CloudWatch metric: %s for ELB Id %s \n", m.MStat.Name, e.Name)
-				anodot_cloudwatch_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetELBMetricProperties(e))
+				name := elbMeasurementName(e.Type, m.MStat.Name)
+				anodot_cloudwatch_metrics := GetAnodotMetric30(name, mr.Timestamps, mr.Values, GetELBMetricProperties(e))
 				anodotMetrics = append(anodotMetrics, anodot_cloudwatch_metrics...)
 			}
 		}
 	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "AzCount" {
+				log.Printf("Processing ELB custom metric AzCount\n")
+				anodotMetrics = append(anodotMetrics, getELBAzCountMetric(elbs)...)
+			}
+		}
+	}
 	return anodotMetrics, nil
 }
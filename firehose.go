@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/firehose"
+)
+
+type DeliveryStream struct {
+	Name   string
+	Region string
+}
+
+func GetDeliveryStreams(session *session.Session) ([]DeliveryStream, error) {
+	streams := make([]DeliveryStream, 0)
+	region := session.Config.Region
+	svc := firehose.New(session)
+
+	input := &firehose.ListDeliveryStreamsInput{
+		Limit: aws.Int64(10000),
+	}
+	for {
+		output, err := svc.ListDeliveryStreams(input)
+		if err != nil {
+			log.Printf("Error occured during Firehose delivery stream fetching %v", err)
+			return streams, err
+		}
+		for _, name := range output.DeliveryStreamNames {
+			streams = append(streams, DeliveryStream{Name: *name, Region: *region})
+		}
+		if !aws.BoolValue(output.HasMoreDeliveryStreams) || len(output.DeliveryStreamNames) == 0 {
+			break
+		}
+		input.ExclusiveStartDeliveryStreamName = output.DeliveryStreamNames[len(output.DeliveryStreamNames)-1]
+	}
+	return streams, nil
+}
+
+func GetDeliveryStreamMetricProperties(stream DeliveryStream) map[string]string {
+	return streamMetricProperties("firehose", stream.Name, stream.Region)
+}
+
+func GetFirehoseCloudwatchMetrics(resource *MonitoredResource, streams []DeliveryStream) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, stream := range streams {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "DeliveryStreamName",
+					Value: stream.Name,
+				},
+			}
+			m.Resource = stream
+			mstatCopy := mstat
+			mstatCopy.Id = "firehose" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func GetFirehoseMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	streams, err := GetDeliveryStreams(ses)
+	if err != nil {
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Firehose delivery streams", len(streams))
+
+	metrics, err := GetFirehoseCloudwatchMetrics(resource, streams)
+	if err != nil {
+		return anodotMetrics, err
+	}
+
+	if len(metrics) == 0 {
+		return anodotMetrics, nil
+	}
+
+	metricdatainput := NewGetMetricDataInput(metrics)
+	metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+	if err != nil {
+		log.Printf("Error during Firehose metrics processing: %v", err)
+		return anodotMetrics, err
+	}
+
+	for _, m := range metrics {
+		for _, mr := range metricdataresults {
+			if *mr.Id == m.MStat.Id {
+				stream := m.Resource.(DeliveryStream)
+				anodot_stream_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetDeliveryStreamMetricProperties(stream))
+				anodotMetrics = append(anodotMetrics, anodot_stream_metrics...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
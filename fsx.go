@@ -0,0 +1,210 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/fsx"
+)
+
+type FSxFileSystem struct {
+	FileSystemId       string
+	FileSystemType     string
+	StorageType        string
+	DeploymentType     string
+	StorageCapacity    float64 // GiB
+	ThroughputCapacity float64 // MB/s
+	Tags               []*fsx.Tag
+	Region             string
+	DimensionTags      []string
+}
+
+func DescribeFSxFileSystems(session *session.Session, resource *MonitoredResource) ([]FSxFileSystem, error) {
+	region := session.Config.Region
+	filesystems := make([]FSxFileSystem, 0)
+	svc := fsx.New(session)
+
+	err := svc.DescribeFileSystemsPages(&fsx.DescribeFileSystemsInput{}, func(page *fsx.DescribeFileSystemsOutput, lastPage bool) bool {
+		for _, fs := range page.FileSystems {
+			filesystem := FSxFileSystem{
+				FileSystemId:    *fs.FileSystemId,
+				FileSystemType:  aws.StringValue(fs.FileSystemType),
+				StorageType:     aws.StringValue(fs.StorageType),
+				DeploymentType:  "None",
+				StorageCapacity: float64(aws.Int64Value(fs.StorageCapacity)),
+				Tags:            fs.Tags,
+				Region:          *region,
+				DimensionTags:   resource.DimensionTags,
+			}
+
+			if fs.WindowsConfiguration != nil {
+				filesystem.DeploymentType = aws.StringValue(fs.WindowsConfiguration.DeploymentType)
+				filesystem.ThroughputCapacity = float64(aws.Int64Value(fs.WindowsConfiguration.ThroughputCapacity))
+			}
+			if fs.LustreConfiguration != nil {
+				filesystem.DeploymentType = aws.StringValue(fs.LustreConfiguration.DeploymentType)
+				// Lustre throughput is provisioned per TiB of storage
+				filesystem.ThroughputCapacity = float64(aws.Int64Value(fs.LustreConfiguration.PerUnitStorageThroughput)) * filesystem.StorageCapacity / 1024
+			}
+			filesystems = append(filesystems, filesystem)
+		}
+		return true
+	})
+	if err != nil {
+		return filesystems, err
+	}
+	return filesystems, nil
+}
+
+func GetFSxDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"FileSystemId",
+		"file_system_type",
+		"storage_type",
+		"deployment_type",
+		"anodot-collector",
+		"region",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetFSxCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "StorageCapacity",
+			Alias:      "StorageCapacity",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "ThroughputCapacity",
+			Alias:      "ThroughputCapacity",
+			TargetType: "average",
+		},
+	}
+}
+
+func GetFSxMetricProperties(fs FSxFileSystem) map[string]string {
+	properties := map[string]string{
+		"service":          "fsx",
+		"FileSystemId":     fs.FileSystemId,
+		"file_system_type": fs.FileSystemType,
+		"storage_type":     fs.StorageType,
+		"deployment_type":  fs.DeploymentType,
+		"anodot-collector": "aws",
+		"region":           fs.Region,
+	}
+
+	for _, v := range fs.Tags {
+		for _, dt := range fs.DimensionTags {
+			if *v.Key == dt {
+				if len(*v.Key) > 50 || len(*v.Value) < 2 {
+					continue
+				}
+				if len(properties) == 17 {
+					break
+				}
+				properties[escape(*v.Key)] = escape(*v.Value)
+			}
+		}
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetFSxCloudwatchMetrics(resource *MonitoredResource, filesystems []FSxFileSystem) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, fs := range filesystems {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "FileSystemId",
+					Value: fs.FileSystemId,
+				},
+			}
+			m.Resource = fs
+			mstatCopy := mstat
+			mstatCopy.Id = "fsx" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func getFSxMetric(filesystems []FSxFileSystem, what string, value func(FSxFileSystem) float64) []metrics3.AnodotMetrics30 {
+	metricList := make([]metrics3.AnodotMetrics30, 0)
+	for _, fs := range filesystems {
+		metricList = append(metricList, metrics3.AnodotMetrics30{
+			Dimensions:   GetFSxMetricProperties(fs),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{what: value(fs)},
+		})
+	}
+	return metricList
+}
+
+func GetFSxMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	filesystems, err := DescribeFSxFileSystems(ses, resource)
+	if err != nil {
+		log.Printf("Cloud not describe FSx file systems: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d FSx file systems", len(filesystems))
+
+	metrics, err := GetFSxCloudwatchMetrics(resource, filesystems)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during FSx metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					fs := m.Resource.(FSxFileSystem)
+					anodot_fsx_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetFSxMetricProperties(fs))
+					anodotMetrics = append(anodotMetrics, anodot_fsx_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "StorageCapacity" {
+				log.Printf("Processing FSx custom metric StorageCapacity\n")
+				anodotMetrics = append(anodotMetrics, getFSxMetric(filesystems, cm, func(fs FSxFileSystem) float64 { return fs.StorageCapacity })...)
+			}
+			if cm == "ThroughputCapacity" {
+				log.Printf("Processing FSx custom metric ThroughputCapacity\n")
+				anodotMetrics = append(anodotMetrics, getFSxMetric(filesystems, cm, func(fs FSxFileSystem) float64 { return fs.ThroughputCapacity })...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// Only metrics which had data points recently are discovered, stale ones are skipped by ListMetrics
+const recentlyActive = "PT3H"
+
+// DiscoverDimensionSets returns dimension sets of a metric having exactly the given dimension keys.
+// ListMetrics also returns metrics with extra dimensions, they are skipped so values are not counted twice
+func DiscoverDimensionSets(cloudwatchSvc *cloudwatch.CloudWatch, namespace, metricName string, keys []string) ([][]Dimension, error) {
+	dimensionSets := make([][]Dimension, 0)
+
+	filters := make([]*cloudwatch.DimensionFilter, 0)
+	for _, k := range keys {
+		filters = append(filters, &cloudwatch.DimensionFilter{
+			Name: aws.String(k),
+		})
+	}
+
+	input := &cloudwatch.ListMetricsInput{
+		Namespace:      aws.String(namespace),
+		MetricName:     aws.String(metricName),
+		Dimensions:     filters,
+		RecentlyActive: aws.String(recentlyActive),
+	}
+	err := cloudwatchSvc.ListMetricsPages(input, func(page *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		for _, m := range page.Metrics {
+			if len(m.Dimensions) != len(keys) {
+				continue
+			}
+			dims := make([]Dimension, 0)
+			for _, d := range m.Dimensions {
+				dims = append(dims, Dimension{
+					Name:  *d.Name,
+					Value: *d.Value,
+				})
+			}
+			dimensionSets = append(dimensionSets, dims)
+		}
+		return true
+	})
+	if err != nil {
+		return dimensionSets, err
+	}
+	return dimensionSets, nil
+}
+
+func genericDimensionName(resource *MonitoredResource, name string) string {
+	if renamed, ok := resource.RenameDimensions[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// GetGenericDimensions are known from config only: keys of explicit dimension sets and discovered keys
+func GetGenericDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"namespace",
+		"region",
+		"anodot-collector",
+	}
+	keys := make([]string, 0)
+	for _, set := range resource.DimensionSets {
+		for _, d := range set {
+			keys = append(keys, genericDimensionName(resource, d.Name))
+		}
+	}
+	for _, k := range resource.DiscoverDimensions {
+		keys = append(keys, genericDimensionName(resource, k))
+	}
+	sort.Strings(keys)
+	return removeDuplicates(append(dims, keys...))
+}
+
+func GetGenericMetricProperties(resource *MonitoredResource, namespace, region string, dimensions []Dimension) map[string]string {
+	properties := map[string]string{
+		"service":          "generic",
+		"namespace":        escape(namespace),
+		"region":           region,
+		"anodot-collector": "aws",
+	}
+
+	for _, d := range dimensions {
+		properties[genericDimensionName(resource, d.Name)] = escape(d.Value)
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetGenericCloudwatchMetrics(cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		dimensionSets := resource.DimensionSets
+		if len(resource.DiscoverDimensions) > 0 {
+			discovered, err := DiscoverDimensionSets(cloudwatchSvc, mstat.Namespace, mstat.Name, resource.DiscoverDimensions)
+			if err != nil {
+				return metrics, err
+			}
+			dimensionSets = append(dimensionSets, discovered...)
+		}
+
+		seen := make(map[string]bool)
+		for _, dims := range dimensionSets {
+			// Dimension set configured explicitly can be discovered as well
+			key := ""
+			for _, d := range dims {
+				key += d.Name + "=" + d.Value + ";"
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			m := MetricToFetch{}
+			m.Dimensions = dims
+			m.Resource = dims
+			mstatCopy := mstat
+			mstatCopy.Id = "generic" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func GetGenericMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	region := *session.Config.Region
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	metrics, err := GetGenericCloudwatchMetrics(cloudwatchSvc, resource)
+	if err != nil {
+		log.Printf("Cloud not discover Generic metrics: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Got %d Generic metrics to fetch", len(metrics))
+	if len(metrics) == 0 {
+		return anodotMetrics, nil
+	}
+
+	metricdatainput := NewGetMetricDataInput(metrics)
+	metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+	if err != nil {
+		log.Printf("Error during Generic metrics processing: %v", err)
+		return anodotMetrics, err
+	}
+
+	for _, m := range metrics {
+		for _, mr := range metricdataresults {
+			if *mr.Id == m.MStat.Id {
+				dims := m.Resource.([]Dimension)
+				anodot_generic_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetGenericMetricProperties(resource, m.MStat.Namespace, region, dims))
+				anodotMetrics = append(anodotMetrics, anodot_generic_metrics...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/globalaccelerator"
+)
+
+type Accelerator struct {
+	Id            string
+	Name          string
+	Status        string
+	Enabled       bool
+	IpAddressType string
+}
+
+func GetAccelerators(ses *session.Session) ([]Accelerator, error) {
+	accelerators := make([]Accelerator, 0)
+	// Global Accelerator API is served from its home region only
+	svc := globalaccelerator.New(ses, aws.NewConfig().WithRegion(globalServiceHomeRegions["GlobalAccelerator"]))
+
+	err := svc.ListAcceleratorsPages(&globalaccelerator.ListAcceleratorsInput{}, func(page *globalaccelerator.ListAcceleratorsOutput, lastPage bool) bool {
+		for _, a := range page.Accelerators {
+			arn := *a.AcceleratorArn
+			accelerators = append(accelerators, Accelerator{
+				// CloudWatch Accelerator dimension is the id part of arn:aws:globalaccelerator::<account>:accelerator/<id>
+				Id:            arn[strings.LastIndex(arn, "/")+1:],
+				Name:          aws.StringValue(a.Name),
+				Status:        aws.StringValue(a.Status),
+				Enabled:       aws.BoolValue(a.Enabled),
+				IpAddressType: aws.StringValue(a.IpAddressType),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return accelerators, err
+	}
+	return accelerators, nil
+}
+
+func GetGlobalAcceleratorDimensions() []string {
+	return []string{
+		"service",
+		"accelerator_id",
+		"accelerator_name",
+		"status",
+		"enabled",
+		"ip_address_type",
+		"anodot-collector",
+	}
+}
+
+func GetGlobalAcceleratorCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "AcceleratorCount",
+			Alias:      "AcceleratorCount",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetAcceleratorMetricProperties(a Accelerator) map[string]string {
+	properties := map[string]string{
+		"service":          "globalaccelerator",
+		"accelerator_id":   a.Id,
+		"accelerator_name": escape(a.Name),
+		"status":           a.Status,
+		"enabled":          strconv.FormatBool(a.Enabled),
+		"ip_address_type":  a.IpAddressType,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetGlobalAcceleratorCloudwatchMetrics(resource *MonitoredResource, accelerators []Accelerator) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, a := range accelerators {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "Accelerator",
+					Value: a.Id,
+				},
+			}
+			m.Resource = a
+			mstatCopy := mstat
+			mstatCopy.Id = "accelerator" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func getAcceleratorCount(accelerators []Accelerator) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, a := range accelerators {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetAcceleratorMetricProperties(a),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"AcceleratorCount": 1},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetGlobalAcceleratorMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	cloudwatchSvc = GetHomeRegionCloudWatch("GlobalAccelerator", cloudwatchSvc, resource)
+
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	accelerators, err := GetAccelerators(ses)
+	if err != nil {
+		log.Printf("Cloud not list Global Accelerators: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Global Accelerators", len(accelerators))
+
+	metrics, err := GetGlobalAcceleratorCloudwatchMetrics(resource, accelerators)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during Global Accelerator metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					a := m.Resource.(Accelerator)
+					anodot_accelerator_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetAcceleratorMetricProperties(a))
+					anodotMetrics = append(anodotMetrics, anodot_accelerator_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "AcceleratorCount" {
+				log.Printf("Processing GlobalAccelerator custom metric AcceleratorCount\n")
+				anodotMetrics = append(anodotMetrics, getAcceleratorCount(accelerators)...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -45,15 +45,21 @@ func GetStreamDimensions() []string {
 	}
 }
 
-func GetStreamMetricProperties(stream KinesisStream) map[string]string {
+// GetStreamDimensions and streamMetricProperties are shared by all streaming services (Kinesis, Firehose, MSK),
+// so their spend can be compared on the same dimensions
+func streamMetricProperties(service, name, region string) map[string]string {
 	return map[string]string{
-		"service":          "kinesis",
-		"StreamName":       stream.Name,
+		"service":          service,
+		"StreamName":       name,
 		"anodot-collector": "aws",
-		"region":           stream.Region,
+		"region":           region,
 	}
 }
 
+func GetStreamMetricProperties(stream KinesisStream) map[string]string {
+	return streamMetricProperties("kinesis", stream.Name, stream.Region)
+}
+
 func GetKinesisStreamCloudwatchMetrics(resource *MonitoredResource, streams []KinesisStream) ([]MetricToFetch, error) {
 	metrics := make([]MetricToFetch, 0)
 
@@ -78,6 +78,8 @@ func LambdaHandler() {
 	var wg sync.WaitGroup
 
 	schemaIds = make(map[string]string, 0)
+	// Lambda container may be reused, checkpoints of a failed run must not be saved by the next one
+	checkpoints = &CheckpointList{}
 
 	c, err := GetConfig()
 	if err != nil {
@@ -165,6 +167,11 @@ func LambdaHandler() {
 		log.Print("No any metrics to push ")
 	}
 
+	err = checkpoints.Save()
+	if err != nil {
+		log.Fatalf("Could not save checkpoints: %v", err)
+	}
+
 }
 
 func main() {
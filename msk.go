@@ -0,0 +1,267 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/kafka"
+)
+
+type MskBroker struct {
+	ClusterName  string
+	BrokerId     string
+	InstanceType string
+	KafkaVersion string
+	VolumeSize   int64
+	Region       string
+}
+
+type MskCluster struct {
+	ClusterName  string
+	InstanceType string
+	KafkaVersion string
+	BrokersCount int64
+	Brokers      []MskBroker
+	Region       string
+}
+
+func GetMskClusters(session *session.Session) ([]MskCluster, error) {
+	region := session.Config.Region
+	clusters := make([]MskCluster, 0)
+	svc := kafka.New(session)
+	rawclusters := make([]*kafka.ClusterInfo, 0)
+
+	input := &kafka.ListClustersInput{}
+	for {
+		output, err := svc.ListClusters(input)
+		if err != nil {
+			return clusters, err
+		}
+		rawclusters = append(rawclusters, output.ClusterInfoList...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	for _, c := range rawclusters {
+		cluster := MskCluster{
+			ClusterName:  *c.ClusterName,
+			BrokersCount: aws.Int64Value(c.NumberOfBrokerNodes),
+			KafkaVersion: "None",
+			Brokers:      make([]MskBroker, 0),
+			Region:       *region,
+		}
+
+		var volumeSize int64
+		if c.BrokerNodeGroupInfo != nil {
+			cluster.InstanceType = aws.StringValue(c.BrokerNodeGroupInfo.InstanceType)
+			if c.BrokerNodeGroupInfo.StorageInfo != nil && c.BrokerNodeGroupInfo.StorageInfo.EbsStorageInfo != nil {
+				volumeSize = aws.Int64Value(c.BrokerNodeGroupInfo.StorageInfo.EbsStorageInfo.VolumeSize)
+			}
+		}
+		if c.CurrentBrokerSoftwareInfo != nil && c.CurrentBrokerSoftwareInfo.KafkaVersion != nil {
+			cluster.KafkaVersion = *c.CurrentBrokerSoftwareInfo.KafkaVersion
+		}
+
+		// Nodes can be listed for active clusters only
+		if aws.StringValue(c.State) == kafka.ClusterStateActive {
+			nodesInput := &kafka.ListNodesInput{
+				ClusterArn: c.ClusterArn,
+			}
+			for {
+				output, err := svc.ListNodes(nodesInput)
+				if err != nil {
+					return clusters, err
+				}
+				for _, n := range output.NodeInfoList {
+					if n.BrokerNodeInfo == nil || n.BrokerNodeInfo.BrokerId == nil {
+						continue
+					}
+					cluster.Brokers = append(cluster.Brokers, MskBroker{
+						ClusterName:  cluster.ClusterName,
+						BrokerId:     strconv.FormatFloat(*n.BrokerNodeInfo.BrokerId, 'f', -1, 64),
+						InstanceType: cluster.InstanceType,
+						KafkaVersion: cluster.KafkaVersion,
+						VolumeSize:   volumeSize,
+						Region:       cluster.Region,
+					})
+				}
+				if output.NextToken == nil {
+					break
+				}
+				nodesInput.NextToken = output.NextToken
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+func GetMskDimensions() []string {
+	return append(GetStreamDimensions(),
+		"broker_id",
+		"broker_instance_type",
+		"kafka_version",
+	)
+}
+
+func GetMskCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "BrokersCount",
+			Alias:      "BrokersCount",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "BrokerStorage",
+			Alias:      "BrokerStorage",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetMskClusterMetricProperties(c MskCluster) map[string]string {
+	properties := streamMetricProperties("msk", c.ClusterName, c.Region)
+	properties["broker_instance_type"] = c.InstanceType
+	properties["kafka_version"] = c.KafkaVersion
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetMskBrokerMetricProperties(b MskBroker) map[string]string {
+	properties := streamMetricProperties("msk", b.ClusterName, b.Region)
+	properties["broker_id"] = b.BrokerId
+	properties["broker_instance_type"] = b.InstanceType
+	properties["kafka_version"] = b.KafkaVersion
+
+	for k, v := range properties {
+		// broker ids are short numbers, keep them
+		if k == "broker_id" {
+			continue
+		}
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetMskCloudwatchMetrics(resource *MonitoredResource, clusters []MskCluster) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, c := range clusters {
+			for _, b := range c.Brokers {
+				m := MetricToFetch{}
+				m.Dimensions = []Dimension{
+					Dimension{
+						Name:  "Cluster Name",
+						Value: b.ClusterName,
+					},
+					Dimension{
+						Name:  "Broker ID",
+						Value: b.BrokerId,
+					},
+				}
+				m.Resource = b
+				mstatCopy := mstat
+				mstatCopy.Id = "msk" + strconv.Itoa(len(metrics))
+				m.MStat = mstatCopy
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func getMskBrokersCount(clusters []MskCluster) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, c := range clusters {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetMskClusterMetricProperties(c),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"BrokersCount": float64(c.BrokersCount)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func getMskBrokerStorage(clusters []MskCluster) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, c := range clusters {
+		for _, b := range c.Brokers {
+			metric := metrics3.AnodotMetrics30{
+				Dimensions:   GetMskBrokerMetricProperties(b),
+				Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+				Measurements: map[string]float64{"BrokerStorage": float64(b.VolumeSize)},
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+func GetMskMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	clusters, err := GetMskClusters(ses)
+	if err != nil {
+		log.Printf("Cloud not list MSK clusters: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d MSK clusters", len(clusters))
+
+	metrics, err := GetMskCloudwatchMetrics(resource, clusters)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during MSK metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					b := m.Resource.(MskBroker)
+					anodot_msk_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetMskBrokerMetricProperties(b))
+					anodotMetrics = append(anodotMetrics, anodot_msk_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "BrokersCount" {
+				log.Printf("Processing MSK custom metric BrokersCount\n")
+				anodotMetrics = append(anodotMetrics, getMskBrokersCount(clusters)...)
+			}
+			if cm == "BrokerStorage" {
+				log.Printf("Processing MSK custom metric BrokerStorage\n")
+				anodotMetrics = append(anodotMetrics, getMskBrokerStorage(clusters)...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type ElasticIp struct {
+	AllocationId string
+	Attached     bool
+	Region       string
+}
+
+type VpcEndpoint struct {
+	VpcEndpointId    string
+	ServiceName      string
+	AvailabilityZone string
+	Region           string
+}
+
+type TransitGatewayAttachment struct {
+	AttachmentId     string
+	TransitGatewayId string
+	ResourceType     string
+	State            string
+	Tags             []*ec2.Tag
+	Region           string
+	DimensionTags    []string
+}
+
+func DescribeElasticIps(ec2svc *ec2.EC2, region string) ([]ElasticIp, error) {
+	ips := make([]ElasticIp, 0)
+	output, err := ec2svc.DescribeAddresses(&ec2.DescribeAddressesInput{})
+	if err != nil {
+		return ips, err
+	}
+
+	for _, a := range output.Addresses {
+		ip := ElasticIp{
+			AllocationId: aws.StringValue(a.PublicIp),
+			Attached:     a.AssociationId != nil || a.InstanceId != nil || a.NetworkInterfaceId != nil,
+			Region:       region,
+		}
+		if a.AllocationId != nil {
+			ip.AllocationId = *a.AllocationId
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+func DescribeInterfaceVpcEndpoints(ec2svc *ec2.EC2, region string) ([]VpcEndpoint, error) {
+	endpoints := make([]VpcEndpoint, 0)
+	rawendpoints := make([]*ec2.VpcEndpoint, 0)
+
+	input := &ec2.DescribeVpcEndpointsInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("vpc-endpoint-type"),
+				Values: []*string{aws.String("Interface")},
+			},
+		},
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		output, err := ec2svc.DescribeVpcEndpoints(input)
+		if err != nil {
+			return endpoints, err
+		}
+		rawendpoints = append(rawendpoints, output.VpcEndpoints...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	subnetIds := make([]string, 0)
+	for _, e := range rawendpoints {
+		subnetIds = append(subnetIds, aws.StringValueSlice(e.SubnetIds)...)
+	}
+	if len(subnetIds) == 0 {
+		return endpoints, nil
+	}
+
+	// Interface endpoints are billed per AZ, AZ is taken from the subnets endpoint is placed in.
+	// Endpoints often share subnets, each subnet is described once.
+	subnetsAz, err := GetSubnetsAz(ec2svc, aws.StringSlice(removeDuplicates(subnetIds)))
+	if err != nil {
+		return endpoints, err
+	}
+
+	for _, e := range rawendpoints {
+		for _, s := range e.SubnetIds {
+			endpoints = append(endpoints, VpcEndpoint{
+				VpcEndpointId:    *e.VpcEndpointId,
+				ServiceName:      *e.ServiceName,
+				AvailabilityZone: subnetsAz[*s],
+				Region:           region,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+// GetSubnetsAz returns availability zone of each subnet
+func GetSubnetsAz(ec2svc *ec2.EC2, subnetIds []*string) (map[string]string, error) {
+	subnetsAz := make(map[string]string)
+	err := ec2svc.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{SubnetIds: subnetIds},
+		func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+			for _, s := range page.Subnets {
+				subnetsAz[*s.SubnetId] = *s.AvailabilityZone
+			}
+			return true
+		})
+	return subnetsAz, err
+}
+
+func DescribeTransitGatewayAttachments(ec2svc *ec2.EC2, region string, resource *MonitoredResource) ([]TransitGatewayAttachment, error) {
+	attachments := make([]TransitGatewayAttachment, 0)
+	rawattachments := make([]*ec2.TransitGatewayAttachment, 0)
+
+	input := &ec2.DescribeTransitGatewayAttachmentsInput{
+		MaxResults: aws.Int64(1000),
+	}
+	for {
+		output, err := ec2svc.DescribeTransitGatewayAttachments(input)
+		if err != nil {
+			return attachments, err
+		}
+		rawattachments = append(rawattachments, output.TransitGatewayAttachments...)
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	for _, a := range rawattachments {
+		attachments = append(attachments, TransitGatewayAttachment{
+			AttachmentId:     *a.TransitGatewayAttachmentId,
+			TransitGatewayId: *a.TransitGatewayId,
+			ResourceType:     aws.StringValue(a.ResourceType),
+			State:            aws.StringValue(a.State),
+			Tags:             a.Tags,
+			Region:           region,
+			DimensionTags:    resource.DimensionTags,
+		})
+	}
+	return attachments, nil
+}
+
+func GetNetworkDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"resource_type",
+		"attached",
+		"endpoint_service",
+		"availability_zone",
+		"transit_gateway_id",
+		"attachment_id",
+		"attachment_type",
+		"state",
+		"region",
+		"anodot-collector",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetNetworkCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "ElasticIpCount",
+			Alias:      "ElasticIpCount",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "VpcEndpointCount",
+			Alias:      "VpcEndpointCount",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetTransitGatewayAttachmentProperties(a TransitGatewayAttachment) map[string]string {
+	properties := map[string]string{
+		"service":            "network",
+		"resource_type":      "transit_gateway_attachment",
+		"transit_gateway_id": a.TransitGatewayId,
+		"attachment_id":      a.AttachmentId,
+		"attachment_type":    a.ResourceType,
+		"state":              a.State,
+		"region":             a.Region,
+		"anodot-collector":   "aws",
+	}
+
+	for _, v := range a.Tags {
+		for _, dt := range a.DimensionTags {
+			if *v.Key == dt {
+				if len(*v.Key) > 50 || len(*v.Value) < 2 {
+					continue
+				}
+				if len(properties) == 17 {
+					break
+				}
+				properties[escape(*v.Key)] = escape(*v.Value)
+			}
+		}
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getElasticIpCountMetric(ips []ElasticIp, region string) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	counts := map[bool]int{true: 0, false: 0}
+	for _, ip := range ips {
+		counts[ip.Attached]++
+	}
+
+	for attached, count := range counts {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions: map[string]string{
+				"service":          "network",
+				"resource_type":    "elastic_ip",
+				"attached":         strconv.FormatBool(attached),
+				"region":           region,
+				"anodot-collector": "aws",
+			},
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"ElasticIpCount": float64(count)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func getVpcEndpointCountMetric(endpoints []VpcEndpoint) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	counts := make(map[VpcEndpoint]int)
+	for _, e := range endpoints {
+		key := VpcEndpoint{ServiceName: e.ServiceName, AvailabilityZone: e.AvailabilityZone, Region: e.Region}
+		counts[key]++
+	}
+
+	for e, count := range counts {
+		properties := map[string]string{
+			"service":           "network",
+			"resource_type":     "vpc_endpoint",
+			"endpoint_service":  e.ServiceName,
+			"availability_zone": e.AvailabilityZone,
+			"region":            e.Region,
+			"anodot-collector":  "aws",
+		}
+		for k, v := range properties {
+			if len(v) > 50 || len(v) < 2 {
+				delete(properties, k)
+			}
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   properties,
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"VpcEndpointCount": float64(count)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetTransitGatewayCloudwatchMetrics(resource *MonitoredResource, attachments []TransitGatewayAttachment) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, a := range attachments {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "TransitGateway",
+					Value: a.TransitGatewayId,
+				},
+				Dimension{
+					Name:  "TransitGatewayAttachment",
+					Value: a.AttachmentId,
+				},
+			}
+			m.Resource = a
+			mstatCopy := mstat
+			mstatCopy.Id = "tgw" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func GetNetworkMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	region := *session.Config.Region
+	ec2svc := ec2.New(session)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "ElasticIpCount" {
+				log.Printf("Processing Network custom metric ElasticIpCount\n")
+				ips, err := DescribeElasticIps(ec2svc, region)
+				if err != nil {
+					log.Printf("Cloud not describe Elastic IPs: %v", err)
+					return anodotMetrics, err
+				}
+				anodotMetrics = append(anodotMetrics, getElasticIpCountMetric(ips, region)...)
+			}
+			if cm == "VpcEndpointCount" {
+				log.Printf("Processing Network custom metric VpcEndpointCount\n")
+				endpoints, err := DescribeInterfaceVpcEndpoints(ec2svc, region)
+				if err != nil {
+					log.Printf("Cloud not describe VPC endpoints: %v", err)
+					return anodotMetrics, err
+				}
+				anodotMetrics = append(anodotMetrics, getVpcEndpointCountMetric(endpoints)...)
+			}
+		}
+	}
+
+	if len(resource.Metrics) == 0 {
+		return anodotMetrics, nil
+	}
+
+	attachments, err := DescribeTransitGatewayAttachments(ec2svc, region, resource)
+	if err != nil {
+		log.Printf("Cloud not describe Transit Gateway attachments: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Transit Gateway attachments", len(attachments))
+
+	metrics, err := GetTransitGatewayCloudwatchMetrics(resource, attachments)
+	if err != nil {
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during Transit Gateway metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					a := m.Resource.(TransitGatewayAttachment)
+					anodot_tgw_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetTransitGatewayAttachmentProperties(a))
+					anodotMetrics = append(anodotMetrics, anodot_tgw_metrics...)
+				}
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
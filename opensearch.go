@@ -0,0 +1,224 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elasticsearchservice"
+)
+
+// DescribeElasticsearchDomains accepts at most 5 domain names per call
+const openSearchDescribeBatch = 5
+
+type OpenSearchDomain struct {
+	DomainName    string
+	AccountId     string
+	EngineVersion string
+	InstanceType  string
+	InstanceCount int64
+	VolumeType    string
+	VolumeSize    int64
+	Region        string
+}
+
+func GetOpenSearchDomains(session *session.Session) ([]OpenSearchDomain, error) {
+	region := session.Config.Region
+	domains := make([]OpenSearchDomain, 0)
+	svc := elasticsearchservice.New(session)
+
+	names, err := svc.ListDomainNames(&elasticsearchservice.ListDomainNamesInput{})
+	if err != nil {
+		return domains, err
+	}
+
+	domainNames := make([]*string, 0)
+	for _, d := range names.DomainNames {
+		domainNames = append(domainNames, d.DomainName)
+	}
+
+	for start := 0; start < len(domainNames); start += openSearchDescribeBatch {
+		end := start + openSearchDescribeBatch
+		if end > len(domainNames) {
+			end = len(domainNames)
+		}
+		output, err := svc.DescribeElasticsearchDomains(&elasticsearchservice.DescribeElasticsearchDomainsInput{
+			DomainNames: domainNames[start:end],
+		})
+		if err != nil {
+			return domains, err
+		}
+
+		for _, d := range output.DomainStatusList {
+			domain := OpenSearchDomain{
+				DomainName:    *d.DomainName,
+				EngineVersion: aws.StringValue(d.ElasticsearchVersion),
+				VolumeType:    "None",
+				Region:        *region,
+			}
+
+			// CloudWatch metrics of a domain are keyed by the owner account id (ClientId dimension)
+			if a, err := arn.Parse(*d.ARN); err == nil {
+				domain.AccountId = a.AccountID
+			}
+
+			if d.ElasticsearchClusterConfig != nil {
+				domain.InstanceType = aws.StringValue(d.ElasticsearchClusterConfig.InstanceType)
+				domain.InstanceCount = aws.Int64Value(d.ElasticsearchClusterConfig.InstanceCount)
+			}
+
+			if d.EBSOptions != nil && aws.BoolValue(d.EBSOptions.EBSEnabled) {
+				domain.VolumeType = aws.StringValue(d.EBSOptions.VolumeType)
+				domain.VolumeSize = aws.Int64Value(d.EBSOptions.VolumeSize)
+			}
+			domains = append(domains, domain)
+		}
+	}
+	return domains, nil
+}
+
+func GetOpenSearchDimensions() []string {
+	return []string{
+		"service",
+		"domain_name",
+		"engine_version",
+		"instance_type",
+		"volume_type",
+		"region",
+		"anodot-collector",
+	}
+}
+
+func GetOpenSearchCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "InstanceCount",
+			Alias:      "InstanceCount",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "VolumeSize",
+			Alias:      "VolumeSize",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetOpenSearchMetricProperties(d OpenSearchDomain) map[string]string {
+	properties := map[string]string{
+		"service":          "opensearch",
+		"domain_name":      d.DomainName,
+		"engine_version":   d.EngineVersion,
+		"instance_type":    d.InstanceType,
+		"volume_type":      d.VolumeType,
+		"region":           d.Region,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetOpenSearchCloudwatchMetrics(resource *MonitoredResource, domains []OpenSearchDomain) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+	for _, mstat := range resource.Metrics {
+		for _, d := range domains {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "DomainName",
+					Value: d.DomainName,
+				},
+				Dimension{
+					Name:  "ClientId",
+					Value: d.AccountId,
+				},
+			}
+			m.Resource = d
+			mstatCopy := mstat
+			mstatCopy.Id = "opensearch" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func getOpenSearchDomainMetric(domains []OpenSearchDomain, what string, value func(OpenSearchDomain) int64) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, d := range domains {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetOpenSearchMetricProperties(d),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{what: float64(value(d))},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetOpenSearchMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	domains, err := GetOpenSearchDomains(ses)
+	if err != nil {
+		log.Printf("Cloud not describe OpenSearch domains: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d OpenSearch domains", len(domains))
+
+	metrics, err := GetOpenSearchCloudwatchMetrics(resource, domains)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during OpenSearch metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					d := m.Resource.(OpenSearchDomain)
+					anodot_opensearch_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetOpenSearchMetricProperties(d))
+					anodotMetrics = append(anodotMetrics, anodot_opensearch_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "InstanceCount" {
+				log.Printf("Processing OpenSearch custom metric InstanceCount\n")
+				anodotMetrics = append(anodotMetrics, getOpenSearchDomainMetric(domains, "InstanceCount", func(d OpenSearchDomain) int64 {
+					return d.InstanceCount
+				})...)
+			}
+			if cm == "VolumeSize" {
+				log.Printf("Processing OpenSearch custom metric VolumeSize\n")
+				anodotMetrics = append(anodotMetrics, getOpenSearchDomainMetric(domains, "VolumeSize", func(d OpenSearchDomain) int64 {
+					return d.VolumeSize
+				})...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Price table columns, see scripts/ec2_price_table.py
+const (
+	priceRegionColumn = iota
+	priceInstanceTypeColumn
+	priceOsColumn
+	priceTenancyColumn
+	pricePerHourColumn
+	priceColumns
+)
+
+// Operating system names of price list by EC2 platform details
+var priceListOs = map[string]string{
+	"Linux/UNIX":               "Linux",
+	"Windows":                  "Windows",
+	"Red Hat Enterprise Linux": "RHEL",
+	"SUSE Linux":               "SUSE",
+}
+
+// Tenancy names of price list by EC2 placement tenancy
+var priceListTenancy = map[string]string{
+	"default":   "Shared",
+	"dedicated": "Dedicated",
+	"host":      "Host",
+}
+
+// OnDemandPrices is price per hour keyed by region, instance type, operating system and tenancy
+type OnDemandPrices map[string]float64
+
+func onDemandPriceKey(region, instanceType, osName, tenancy string) string {
+	return strings.Join([]string{region, instanceType, osName, tenancy}, "/")
+}
+
+func (p OnDemandPrices) Get(ins Instance) (float64, bool) {
+	osName, ok := priceListOs[ins.Platform]
+	if !ok && strings.HasPrefix(ins.Platform, "Windows") {
+		// Windows with SQL Server etc., licence of pre installed software is not included
+		osName, ok = "Windows", true
+	}
+	if !ok {
+		return 0, false
+	}
+	price, ok := p[onDemandPriceKey(ins.Region, ins.InstanceType, osName, priceListTenancy[ins.Tenancy])]
+	return price, ok
+}
+
+// openPriceTable opens a local file (bundled into lambda archive) or s3://bucket/key object
+func openPriceTable(path string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(path, "s3://") {
+		return os.Open(path)
+	}
+
+	bucketKey := strings.SplitN(strings.TrimPrefix(path, "s3://"), "/", 2)
+	if len(bucketKey) != 2 {
+		return nil, fmt.Errorf("wrong price table location %s", path)
+	}
+	svc := s3.New(session.New())
+	result, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucketKey[0]),
+		Key:    aws.String(bucketKey[1]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Body, nil
+}
+
+func LoadOnDemandPrices(path string) (OnDemandPrices, error) {
+	prices := make(OnDemandPrices)
+	f, err := openPriceTable(path)
+	if err != nil {
+		return prices, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = priceColumns
+	records, err := r.ReadAll()
+	if err != nil {
+		return prices, err
+	}
+
+	for _, rec := range records {
+		price, err := strconv.ParseFloat(rec[pricePerHourColumn], 64)
+		if err != nil {
+			return prices, fmt.Errorf("wrong price %s of %s: %v", rec[pricePerHourColumn], rec[priceInstanceTypeColumn], err)
+		}
+		prices[onDemandPriceKey(rec[priceRegionColumn], rec[priceInstanceTypeColumn], rec[priceOsColumn], rec[priceTenancyColumn])] = price
+	}
+	return prices, nil
+}
+
+func spotPriceKey(instanceType, az, product string) string {
+	return instanceType + "/" + az + "/" + product
+}
+
+// GetSpotPrices returns current spot prices of instance types keyed by instance type, availability zone and product description
+func (ec2fetcher *EC2Fetcher) GetSpotPrices(instanceTypes []string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	if len(instanceTypes) == 0 {
+		return prices, nil
+	}
+
+	input := &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes: aws.StringSlice(instanceTypes),
+		// Price in effect at the start time is returned for each instance type, zone and product
+		StartTime: aws.Time(time.Now()),
+	}
+	err := ec2fetcher.instanceService.DescribeSpotPriceHistoryPages(input, func(page *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+		for _, p := range page.SpotPriceHistory {
+			price, err := strconv.ParseFloat(aws.StringValue(p.SpotPrice), 64)
+			if err != nil {
+				continue
+			}
+			// VPC products are named e.g. "Linux/UNIX (Amazon VPC)"
+			product := strings.TrimSuffix(aws.StringValue(p.ProductDescription), " (Amazon VPC)")
+			key := spotPriceKey(aws.StringValue(p.InstanceType), aws.StringValue(p.AvailabilityZone), product)
+			if _, ok := prices[key]; !ok {
+				prices[key] = price
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return prices, err
+	}
+	return prices, nil
+}
+
+// SetPrices fills HourlyPrice of instances: spot price for spot instances
+// and on-demand price from price table for normal ones
+func (ec2fetcher *EC2Fetcher) SetPrices(instances ListInstances, priceTable string) error {
+	spotTypes := make([]string, 0)
+	for _, i := range instances {
+		if i.Lifecycle == "spot" {
+			spotTypes = append(spotTypes, i.InstanceType)
+		}
+	}
+	spotPrices, err := ec2fetcher.GetSpotPrices(removeDuplicates(spotTypes))
+	if err != nil {
+		return err
+	}
+
+	onDemandPrices := make(OnDemandPrices)
+	if priceTable != "" {
+		onDemandPrices, err = LoadOnDemandPrices(priceTable)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Printf("WARNING: PriceTable is not configured, on-demand EC2 instances are not priced")
+	}
+
+	unpriced := 0
+	for idx, i := range instances {
+		switch i.Lifecycle {
+		case "spot":
+			instances[idx].HourlyPrice = spotPrices[spotPriceKey(i.InstanceType, i.AvailabilityZone, i.Platform)]
+		case "normal":
+			instances[idx].HourlyPrice, _ = onDemandPrices.Get(i)
+		}
+		if instances[idx].HourlyPrice == 0 {
+			unpriced++
+		}
+	}
+	if unpriced > 0 {
+		log.Printf("WARNING: no price found for %d EC2 instances, they are not reported in HourlyPrice and EstimatedCost", unpriced)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/redshift"
+)
+
+type RedshiftCluster struct {
+	ClusterId     string
+	NodeType      string
+	NumberOfNodes int64
+	Status        string
+	Tags          []*redshift.Tag
+	Region        string
+	DimensionTags []string
+}
+
+func GetRedshiftClusters(session *session.Session, resource *MonitoredResource) ([]RedshiftCluster, error) {
+	region := session.Config.Region
+	clusters := make([]RedshiftCluster, 0)
+	svc := redshift.New(session)
+	rawclusters := make([]*redshift.Cluster, 0)
+
+	input := &redshift.DescribeClustersInput{}
+	for {
+		output, err := svc.DescribeClusters(input)
+		if err != nil {
+			return clusters, err
+		}
+		rawclusters = append(rawclusters, output.Clusters...)
+		if output.Marker == nil {
+			break
+		}
+		input.Marker = output.Marker
+	}
+
+	for _, c := range rawclusters {
+		clusters = append(clusters, RedshiftCluster{
+			ClusterId:     *c.ClusterIdentifier,
+			NodeType:      *c.NodeType,
+			NumberOfNodes: *c.NumberOfNodes,
+			Status:        *c.ClusterStatus,
+			Tags:          c.Tags,
+			Region:        *region,
+			DimensionTags: resource.DimensionTags,
+		})
+	}
+	return clusters, nil
+}
+
+func GetRedshiftDimensions(resource *MonitoredResource) []string {
+	dims := []string{
+		"service",
+		"cluster_id",
+		"node_type",
+		"cluster_status",
+		"region",
+		"anodot-collector",
+	}
+	return removeDuplicates(append(dims, resource.DimensionTags...))
+}
+
+func GetRedshiftCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "NodesCount",
+			Alias:      "NodesCount",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetRedshiftMetricProperties(c RedshiftCluster) map[string]string {
+	properties := map[string]string{
+		"service":          "redshift",
+		"cluster_id":       c.ClusterId,
+		"node_type":        c.NodeType,
+		"cluster_status":   c.Status,
+		"region":           c.Region,
+		"anodot-collector": "aws",
+	}
+
+	for _, v := range c.Tags {
+		for _, dt := range c.DimensionTags {
+			if *v.Key == dt {
+				if len(*v.Key) > 50 || len(*v.Value) < 2 {
+					continue
+				}
+				if len(properties) == 17 {
+					break
+				}
+				properties[escape(*v.Key)] = escape(*v.Value)
+			}
+		}
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetRedshiftCloudwatchMetrics(resource *MonitoredResource, clusters []RedshiftCluster) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+	for _, mstat := range resource.Metrics {
+		for _, c := range clusters {
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "ClusterIdentifier",
+					Value: c.ClusterId,
+				},
+			}
+			m.Resource = c
+			mstatCopy := mstat
+			mstatCopy.Id = "redshift" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func getRedshiftNodesCount(clusters []RedshiftCluster) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, c := range clusters {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetRedshiftMetricProperties(c),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"NodesCount": float64(c.NumberOfNodes)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetRedshiftMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	clusters, err := GetRedshiftClusters(ses, resource)
+	if err != nil {
+		log.Printf("Cloud not describe Redshift clusters: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Redshift clusters", len(clusters))
+
+	metrics, err := GetRedshiftCloudwatchMetrics(resource, clusters)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during Redshift metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					c := m.Resource.(RedshiftCluster)
+					anodot_redshift_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetRedshiftMetricProperties(c))
+					anodotMetrics = append(anodotMetrics, anodot_redshift_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "NodesCount" {
+				log.Printf("Processing Redshift custom metric NodesCount\n")
+				anodotMetrics = append(anodotMetrics, getRedshiftNodesCount(clusters)...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
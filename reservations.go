@@ -0,0 +1,381 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// Cost Explorer service name of EC2 instances, used to match reservations with EC2 collector
+const ec2CostService = "Amazon Elastic Compute Cloud - Compute"
+
+// Coverage and utilization are available with about a day delay, so last two days are reported
+const reservationsDays = 2
+
+type CommitmentUsage struct {
+	Day            time.Time
+	InstanceFamily string
+	InstanceType   string
+	Region         string
+	Measurement    string
+	// Used and Total are summed up per group, percentage is calculated from them
+	Used  float64
+	Total float64
+}
+
+type commitmentKey struct {
+	Day            time.Time
+	InstanceFamily string
+	InstanceType   string
+	Region         string
+}
+
+func instanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+func parseAmount(s *string) float64 {
+	v, err := strconv.ParseFloat(aws.StringValue(s), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// attribute looks up Cost Explorer attribute ignoring the key case and underscores,
+// since different APIs name them differently (instanceType, INSTANCE_TYPE)
+func attribute(attributes map[string]*string, key string) string {
+	for k, v := range attributes {
+		if strings.EqualFold(strings.ReplaceAll(k, "_", ""), key) {
+			return aws.StringValue(v)
+		}
+	}
+	return ""
+}
+
+func addCommitmentUsage(usages map[commitmentKey]*CommitmentUsage, key commitmentKey, measurement string, used, total float64) {
+	u, ok := usages[key]
+	if !ok {
+		u = &CommitmentUsage{
+			Day:            key.Day,
+			InstanceFamily: key.InstanceFamily,
+			InstanceType:   key.InstanceType,
+			Region:         key.Region,
+			Measurement:    measurement,
+		}
+		usages[key] = u
+	}
+	u.Used += used
+	u.Total += total
+}
+
+func commitmentUsageList(usages map[commitmentKey]*CommitmentUsage) []CommitmentUsage {
+	list := make([]CommitmentUsage, 0)
+	for _, u := range usages {
+		list = append(list, *u)
+	}
+	return list
+}
+
+func reservationsPeriod() *costexplorer.DateInterval {
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	start := end.AddDate(0, 0, -reservationsDays)
+	return &costexplorer.DateInterval{
+		Start: aws.String(start.Format(costExplorerDateFormat)),
+		End:   aws.String(end.Format(costExplorerDateFormat)),
+	}
+}
+
+func GetReservationUtilization(svc *costexplorer.CostExplorer) ([]CommitmentUsage, error) {
+	usages := make(map[commitmentKey]*CommitmentUsage)
+	input := &costexplorer.GetReservationUtilizationInput{
+		TimePeriod:  reservationsPeriod(),
+		Granularity: aws.String(costexplorer.GranularityDaily),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionSubscriptionId)},
+		},
+		Filter: &costexplorer.Expression{
+			Dimensions: &costexplorer.DimensionValues{
+				Key:    aws.String(costexplorer.DimensionService),
+				Values: []*string{aws.String(ec2CostService)},
+			},
+		},
+	}
+
+	for {
+		output, err := svc.GetReservationUtilization(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range output.UtilizationsByTime {
+			day, err := time.Parse(costExplorerDateFormat, *r.TimePeriod.Start)
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range r.Groups {
+				if g.Utilization == nil {
+					continue
+				}
+				key := commitmentKey{
+					Day:            day,
+					InstanceFamily: instanceFamily(attribute(g.Attributes, "instanceType")),
+					Region:         attribute(g.Attributes, "region"),
+				}
+				addCommitmentUsage(usages, key, "ri_utilization_pct",
+					parseAmount(g.Utilization.TotalActualHours), parseAmount(g.Utilization.PurchasedHours))
+			}
+		}
+		if output.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+	return commitmentUsageList(usages), nil
+}
+
+func GetReservationCoverage(svc *costexplorer.CostExplorer) ([]CommitmentUsage, error) {
+	usages := make(map[commitmentKey]*CommitmentUsage)
+	input := &costexplorer.GetReservationCoverageInput{
+		TimePeriod:  reservationsPeriod(),
+		Granularity: aws.String(costexplorer.GranularityDaily),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionInstanceType)},
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionRegion)},
+		},
+		Filter: &costexplorer.Expression{
+			Dimensions: &costexplorer.DimensionValues{
+				Key:    aws.String(costexplorer.DimensionService),
+				Values: []*string{aws.String(ec2CostService)},
+			},
+		},
+	}
+
+	for {
+		output, err := svc.GetReservationCoverage(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range output.CoveragesByTime {
+			day, err := time.Parse(costExplorerDateFormat, *r.TimePeriod.Start)
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range r.Groups {
+				if g.Coverage == nil || g.Coverage.CoverageHours == nil {
+					continue
+				}
+				instanceType := attribute(g.Attributes, "instanceType")
+				key := commitmentKey{
+					Day:            day,
+					InstanceFamily: instanceFamily(instanceType),
+					InstanceType:   instanceType,
+					Region:         attribute(g.Attributes, "region"),
+				}
+				addCommitmentUsage(usages, key, "ri_coverage_pct",
+					parseAmount(g.Coverage.CoverageHours.ReservedHours), parseAmount(g.Coverage.CoverageHours.TotalRunningHours))
+			}
+		}
+		if output.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+	return commitmentUsageList(usages), nil
+}
+
+func GetSavingsPlansUtilization(svc *costexplorer.CostExplorer) ([]CommitmentUsage, error) {
+	usages := make(map[commitmentKey]*CommitmentUsage)
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// Utilization details are reported for the whole requested period, so days are requested one by one
+	for d := reservationsDays; d > 0; d-- {
+		day := end.AddDate(0, 0, -d)
+		input := &costexplorer.GetSavingsPlansUtilizationDetailsInput{
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String(day.Format(costExplorerDateFormat)),
+				End:   aws.String(day.AddDate(0, 0, 1).Format(costExplorerDateFormat)),
+			},
+		}
+		for {
+			output, err := svc.GetSavingsPlansUtilizationDetails(input)
+			if err != nil {
+				return nil, err
+			}
+			for _, sp := range output.SavingsPlansUtilizationDetails {
+				if sp.Utilization == nil {
+					continue
+				}
+				// Compute Savings Plans are not bound to instance family and region
+				family := attribute(sp.Attributes, "instanceFamily")
+				if family == "" {
+					family = "all"
+				}
+				region := attribute(sp.Attributes, "region")
+				if region == "" {
+					region = "all"
+				}
+				key := commitmentKey{
+					Day:            day,
+					InstanceFamily: family,
+					Region:         region,
+				}
+				addCommitmentUsage(usages, key, "sp_utilization_pct",
+					parseAmount(sp.Utilization.UsedCommitment), parseAmount(sp.Utilization.TotalCommitment))
+			}
+			if output.NextToken == nil {
+				break
+			}
+			input.NextToken = output.NextToken
+		}
+	}
+	return commitmentUsageList(usages), nil
+}
+
+func GetSavingsPlansCoverage(svc *costexplorer.CostExplorer) ([]CommitmentUsage, error) {
+	usages := make(map[commitmentKey]*CommitmentUsage)
+	input := &costexplorer.GetSavingsPlansCoverageInput{
+		TimePeriod:  reservationsPeriod(),
+		Granularity: aws.String(costexplorer.GranularityDaily),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionInstanceTypeFamily)},
+			{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(costexplorer.DimensionRegion)},
+		},
+		Filter: &costexplorer.Expression{
+			Dimensions: &costexplorer.DimensionValues{
+				Key:    aws.String(costexplorer.DimensionService),
+				Values: []*string{aws.String(ec2CostService)},
+			},
+		},
+	}
+
+	for {
+		output, err := svc.GetSavingsPlansCoverage(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range output.SavingsPlansCoverages {
+			if c.Coverage == nil || c.TimePeriod == nil {
+				continue
+			}
+			day, err := time.Parse(costExplorerDateFormat, *c.TimePeriod.Start)
+			if err != nil {
+				return nil, err
+			}
+			key := commitmentKey{
+				Day:            day,
+				InstanceFamily: attribute(c.Attributes, "instanceTypeFamily"),
+				Region:         attribute(c.Attributes, "region"),
+			}
+			addCommitmentUsage(usages, key, "sp_coverage_pct",
+				parseAmount(c.Coverage.SpendCoveredBySavingsPlans), parseAmount(c.Coverage.TotalCost))
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	return commitmentUsageList(usages), nil
+}
+
+func GetReservationsDimensions() []string {
+	return []string{
+		"service",
+		"instance_family",
+		"instance_type",
+		"region",
+		"anodot-collector",
+	}
+}
+
+func GetReservationsCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "ri_utilization_pct",
+			Alias:      "RIUtilization",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "ri_coverage_pct",
+			Alias:      "RICoverage",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "sp_utilization_pct",
+			Alias:      "SavingsPlansUtilization",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "sp_coverage_pct",
+			Alias:      "SavingsPlansCoverage",
+			TargetType: "average",
+		},
+	}
+}
+
+func GetReservationsMetricProperties(u CommitmentUsage) map[string]string {
+	properties := map[string]string{
+		"service":          "reservations",
+		"instance_family":  u.InstanceFamily,
+		"instance_type":    u.InstanceType,
+		"region":           u.Region,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getCommitmentMetrics(usages []CommitmentUsage) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, u := range usages {
+		if u.Total == 0 {
+			continue
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetReservationsMetricProperties(u),
+			Timestamp:    metrics3.AnodotTimestamp{u.Day},
+			Measurements: map[string]float64{u.Measurement: u.Used / u.Total * 100},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetReservationsMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	svc := newCostExplorerClient(ses)
+
+	fetchers := map[string]func(*costexplorer.CostExplorer) ([]CommitmentUsage, error){
+		"ri_utilization_pct": GetReservationUtilization,
+		"ri_coverage_pct":    GetReservationCoverage,
+		"sp_utilization_pct": GetSavingsPlansUtilization,
+		"sp_coverage_pct":    GetSavingsPlansCoverage,
+	}
+
+	for _, cm := range resource.CustomMetrics {
+		for _, def := range GetReservationsCustomMetrics() {
+			if cm != def.Name && cm != def.Alias {
+				continue
+			}
+			log.Printf("Processing Reservations custom metric %s\n", def.Alias)
+			usages, err := fetchers[def.Name](svc)
+			if err != nil {
+				log.Printf("Cloud not get %s: %v", def.Alias, err)
+				return metrics, err
+			}
+			metrics = append(metrics, getCommitmentMetrics(usages)...)
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+type HostedZone struct {
+	Id             string
+	Name           string
+	Private        bool
+	RecordSetCount int64
+	QueryLogging   bool
+}
+
+type HealthChecks struct {
+	Type  string
+	Count int64
+}
+
+func GetHostedZones(session *session.Session) ([]HostedZone, error) {
+	zones := make([]HostedZone, 0)
+	svc := route53.New(session)
+
+	err := svc.ListHostedZonesPages(&route53.ListHostedZonesInput{}, func(page *route53.ListHostedZonesOutput, lastPage bool) bool {
+		for _, z := range page.HostedZones {
+			zone := HostedZone{
+				// Id is returned as /hostedzone/<id>, CloudWatch uses bare id
+				Id:             strings.TrimPrefix(*z.Id, "/hostedzone/"),
+				Name:           strings.TrimSuffix(*z.Name, "."),
+				RecordSetCount: aws.Int64Value(z.ResourceRecordSetCount),
+			}
+			if z.Config != nil {
+				zone.Private = aws.BoolValue(z.Config.PrivateZone)
+			}
+			zones = append(zones, zone)
+		}
+		return true
+	})
+	if err != nil {
+		return zones, err
+	}
+
+	// DNSQueries metric is fetched for zones which have query logging configured
+	logged := make(map[string]bool)
+	err = svc.ListQueryLoggingConfigsPages(&route53.ListQueryLoggingConfigsInput{}, func(page *route53.ListQueryLoggingConfigsOutput, lastPage bool) bool {
+		for _, c := range page.QueryLoggingConfigs {
+			logged[*c.HostedZoneId] = true
+		}
+		return true
+	})
+	if err != nil {
+		return zones, err
+	}
+	for i := range zones {
+		zones[i].QueryLogging = logged[zones[i].Id]
+	}
+	return zones, nil
+}
+
+func GetHealthChecks(session *session.Session) ([]HealthChecks, error) {
+	healthChecks := make([]HealthChecks, 0)
+	svc := route53.New(session)
+	byType := make(map[string]int64)
+
+	err := svc.ListHealthChecksPages(&route53.ListHealthChecksInput{}, func(page *route53.ListHealthChecksOutput, lastPage bool) bool {
+		for _, hc := range page.HealthChecks {
+			if hc.HealthCheckConfig != nil {
+				byType[aws.StringValue(hc.HealthCheckConfig.Type)]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return healthChecks, err
+	}
+
+	for t, c := range byType {
+		healthChecks = append(healthChecks, HealthChecks{
+			Type:  t,
+			Count: c,
+		})
+	}
+	return healthChecks, nil
+}
+
+func GetRoute53Dimensions() []string {
+	return []string{
+		"service",
+		"hosted_zone_id",
+		"zone_name",
+		"private_zone",
+		"health_check_type",
+		"anodot-collector",
+	}
+}
+
+func GetRoute53CustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "RecordSetCount",
+			Alias:      "RecordSetCount",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "HealthCheckCount",
+			Alias:      "HealthCheckCount",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetHostedZoneMetricProperties(z HostedZone) map[string]string {
+	properties := map[string]string{
+		"service":          "route53",
+		"hosted_zone_id":   z.Id,
+		"zone_name":        escape(z.Name),
+		"private_zone":     strconv.FormatBool(z.Private),
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetRoute53CloudwatchMetrics(resource *MonitoredResource, zones []HostedZone) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+
+	for _, mstat := range resource.Metrics {
+		for _, z := range zones {
+			if !z.QueryLogging {
+				continue
+			}
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "HostedZoneId",
+					Value: z.Id,
+				},
+			}
+			m.Resource = z
+			mstatCopy := mstat
+			mstatCopy.Id = "route53" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func getRecordSetCount(zones []HostedZone) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, z := range zones {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetHostedZoneMetricProperties(z),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"RecordSetCount": float64(z.RecordSetCount)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func getHealthCheckCount(healthChecks []HealthChecks) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, hc := range healthChecks {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions: map[string]string{
+				"service":           "route53",
+				"health_check_type": hc.Type,
+				"anodot-collector":  "aws",
+			},
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"HealthCheckCount": float64(hc.Count)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetRoute53Metrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	cloudwatchSvc = GetHomeRegionCloudWatch("Route53", cloudwatchSvc, resource)
+
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	zones, err := GetHostedZones(ses)
+	if err != nil {
+		log.Printf("Cloud not list Route53 hosted zones: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d Route53 hosted zones", len(zones))
+
+	metrics, err := GetRoute53CloudwatchMetrics(resource, zones)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during Route53 metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					z := m.Resource.(HostedZone)
+					anodot_route53_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetHostedZoneMetricProperties(z))
+					anodotMetrics = append(anodotMetrics, anodot_route53_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "RecordSetCount" {
+				log.Printf("Processing Route53 custom metric RecordSetCount\n")
+				anodotMetrics = append(anodotMetrics, getRecordSetCount(zones)...)
+			}
+			if cm == "HealthCheckCount" {
+				log.Printf("Processing Route53 custom metric HealthCheckCount\n")
+				healthChecks, err := GetHealthChecks(ses)
+				if err != nil {
+					log.Printf("Cloud not list Route53 health checks: %v", err)
+					return anodotMetrics, err
+				}
+				anodotMetrics = append(anodotMetrics, getHealthCheckCount(healthChecks)...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -3,14 +3,54 @@ package main
 import (
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
+// S3 StorageType dimension values by storage class, ordered so that longer prefixes are matched first.
+// Object overhead kept for objects archived to Glacier and Deep Archive (e.g. GlacierS3ObjectOverhead)
+// is billed at STANDARD rates, other overhead types (e.g. GlacierObjectOverhead) in the class they belong to.
+var s3StorageClasses = []struct {
+	prefix       string
+	storageClass string
+}{
+	{"GlacierS3ObjectOverhead", "STANDARD"},
+	{"DeepArchiveS3ObjectOverhead", "STANDARD"},
+	{"StandardIA", "STANDARD_IA"},
+	{"Standard", "STANDARD"},
+	{"OneZoneIA", "ONEZONE_IA"},
+	{"ReducedRedundancy", "REDUCED_REDUNDANCY"},
+	{"IntelligentTiering", "INTELLIGENT_TIERING"},
+	{"GlacierInstantRetrieval", "GLACIER_IR"},
+	{"Glacier", "GLACIER"},
+	{"DeepArchive", "DEEP_ARCHIVE"},
+	{"AllStorageTypes", "ALL"},
+}
+
+func s3StorageClass(storageType string) string {
+	for _, sc := range s3StorageClasses {
+		if strings.HasPrefix(storageType, sc.prefix) {
+			return sc.storageClass
+		}
+	}
+	return storageType
+}
+
+// Storage metrics are published daily per StorageType, all other S3 metrics are request metrics published per FilterId
+var s3StorageMetrics = map[string]bool{
+	"BucketSizeBytes": true,
+	"NumberOfObjects": true,
+}
+
+// Id of request metrics configuration without filter, created by CreateRequestMetrics option
+const s3EntireBucketFilterId = "EntireBucket"
+
 type S3Metric struct {
 	Name       string
 	Dimensions []Dimension
@@ -20,9 +60,77 @@ type S3 struct {
 	BucketName string
 	Region     string
 	S3Metrics  []S3Metric
+	// Ids of request metrics configurations
+	FilterIds []string
 }
 
-func GetS3Buckets(session *session.Session, listmetrics []*cloudwatch.Metric) ([]S3, error) {
+// GetBucketRegion returns region of a bucket, ListBuckets returns buckets of all regions
+func GetBucketRegion(svc *s3.S3, bucket string) (string, error) {
+	output, err := svc.GetBucketLocation(&s3.GetBucketLocationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Buckets in us-east-1 have empty location constraint, EU is the legacy name of eu-west-1
+	location := aws.StringValue(output.LocationConstraint)
+	switch location {
+	case "":
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	}
+	return location, nil
+}
+
+// GetBucketMetricsFilters returns ids of request metrics configurations of a bucket
+func GetBucketMetricsFilters(svc *s3.S3, bucket string) ([]string, error) {
+	filterIds := make([]string, 0)
+	input := &s3.ListBucketMetricsConfigurationsInput{
+		Bucket: aws.String(bucket),
+	}
+
+	for {
+		output, err := svc.ListBucketMetricsConfigurations(input)
+		if err != nil {
+			return filterIds, err
+		}
+		for _, c := range output.MetricsConfigurationList {
+			filterIds = append(filterIds, *c.Id)
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = output.NextContinuationToken
+	}
+	return filterIds, nil
+}
+
+// CreateEntireBucketMetrics creates request metrics configuration for all objects of a bucket.
+// S3 starts to publish request metrics about 15 minutes after configuration is created.
+func CreateEntireBucketMetrics(svc *s3.S3, bucket string) error {
+	_, err := svc.PutBucketMetricsConfiguration(&s3.PutBucketMetricsConfigurationInput{
+		Bucket: aws.String(bucket),
+		Id:     aws.String(s3EntireBucketFilterId),
+		MetricsConfiguration: &s3.MetricsConfiguration{
+			Id: aws.String(s3EntireBucketFilterId),
+		},
+	})
+	return err
+}
+
+func hasS3RequestMetrics(resource *MonitoredResource) bool {
+	for _, mstat := range resource.Metrics {
+		if !s3StorageMetrics[mstat.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetS3Buckets returns buckets located in the session region, S3 metrics are published to CloudWatch of bucket region only
+func GetS3Buckets(session *session.Session, listmetrics []*cloudwatch.Metric, resource *MonitoredResource) ([]S3, error) {
 	s3list := make([]S3, 0)
 	region := session.Config.Region
 
@@ -33,48 +141,75 @@ func GetS3Buckets(session *session.Session, listmetrics []*cloudwatch.Metric) ([
 		return s3list, err
 	}
 
-	var dbucketname string
+	bucketMetrics := make(map[string][]S3Metric)
+	for _, m := range listmetrics {
+		dimensions := make([]Dimension, 0)
+		bucketName := ""
+		for _, d := range m.Dimensions {
+			if *d.Name == "BucketName" {
+				bucketName = *d.Value
+			}
+			dimensions = append(dimensions, Dimension{
+				Name:  *d.Name,
+				Value: *d.Value,
+			})
+		}
+		if bucketName == "" {
+			continue
+		}
+		bucketMetrics[bucketName] = append(bucketMetrics[bucketName], S3Metric{
+			Name:       *m.MetricName,
+			Dimensions: dimensions,
+		})
+	}
 
 	for _, s := range result.Buckets {
-		s3Metrics := make([]S3Metric, 0)
-		for _, m := range listmetrics {
-
-			dimensions := make([]Dimension, 0)
+		// Buckets of other regions have no metrics listed in this region, their location is not looked up
+		if _, ok := bucketMetrics[*s.Name]; !ok {
+			continue
+		}
+		bucketRegion, err := GetBucketRegion(svc, *s.Name)
+		if err != nil {
+			log.Printf("Could not get location of S3 bucket %s: %v", *s.Name, err)
+			continue
+		}
+		if bucketRegion != *region {
+			continue
+		}
 
-			for _, d := range m.Dimensions {
+		bucket := S3{
+			BucketName: *s.Name,
+			Region:     bucketRegion,
+			S3Metrics:  bucketMetrics[*s.Name],
+		}
 
-				if *d.Name == "BucketName" {
-					dbucketname = *d.Value
-					if *d.Value == *s.Name {
-						for _, d2 := range m.Dimensions {
-							dimensions = append(dimensions, Dimension{
-								Name:  *d2.Name,
-								Value: *d2.Value,
-							})
-						}
-					}
+		if hasS3RequestMetrics(resource) {
+			bucket.FilterIds, err = GetBucketMetricsFilters(svc, *s.Name)
+			if err != nil {
+				log.Printf("Could not list metrics configurations of S3 bucket %s: %v", *s.Name, err)
+			} else if len(bucket.FilterIds) == 0 && resource.CreateRequestMetrics {
+				if err := CreateEntireBucketMetrics(svc, *s.Name); err != nil {
+					log.Printf("Could not create metrics configuration of S3 bucket %s: %v", *s.Name, err)
+				} else {
+					log.Printf("Created %s metrics configuration of S3 bucket %s", s3EntireBucketFilterId, *s.Name)
+					bucket.FilterIds = append(bucket.FilterIds, s3EntireBucketFilterId)
 				}
 			}
-			if dbucketname == *s.Name {
-				s3Metrics = append(s3Metrics, S3Metric{
-					Name:       *m.MetricName,
-					Dimensions: dimensions,
-				})
-			}
 		}
-		s3list = append(s3list, S3{
-			BucketName: *s.Name,
-			Region:     *region,
-			S3Metrics:  s3Metrics,
-		})
+		s3list = append(s3list, bucket)
 	}
 
-	return s3list, err
+	return s3list, nil
 }
 
 func GetS3Dimensions() []string {
 	return []string{
 		"storage_type",
+		"storage_class",
+		"filter_id",
+		"prefix",
+		"age_bucket",
+		"report_source",
 		"service",
 		"bucket_name",
 		"region",
@@ -82,6 +217,21 @@ func GetS3Dimensions() []string {
 	}
 }
 
+func GetS3CustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "object_count",
+			Alias:      "ObjectCount",
+			TargetType: "sum",
+		},
+		CustomMetricDefinition{
+			Name:       "object_bytes",
+			Alias:      "ObjectBytes",
+			TargetType: "sum",
+		},
+	}
+}
+
 func GetS3MetricProperties(bucket S3) map[string]string {
 	properties := map[string]string{
 		"service":          "s3",
@@ -102,17 +252,43 @@ func GetS3CloudwatchMetrics(resource *MonitoredResource, buckets []S3) ([]Metric
 	metrics := make([]MetricToFetch, 0)
 	for _, mstat := range resource.Metrics {
 		for _, bucket := range buckets {
-			m := MetricToFetch{}
+			if !s3StorageMetrics[mstat.Name] {
+				// Request metrics are fetched per metrics configuration, they may be not listed yet
+				// for configurations without recent requests
+				for _, filterId := range bucket.FilterIds {
+					m := MetricToFetch{}
+					m.Dimensions = []Dimension{
+						Dimension{
+							Name:  "BucketName",
+							Value: bucket.BucketName,
+						},
+						Dimension{
+							Name:  "FilterId",
+							Value: filterId,
+						},
+					}
+					m.Resource = bucket
+					mstatCopy := mstat
+					mstatCopy.Id = "s3" + strconv.Itoa(len(metrics))
+					m.MStat = mstatCopy
+					metrics = append(metrics, m)
+				}
+				continue
+			}
+
+			// One series per storage type
 			for _, s3m := range bucket.S3Metrics {
-				if s3m.Name == mstat.Name {
-					m.Dimensions = s3m.Dimensions
+				if s3m.Name != mstat.Name {
+					continue
 				}
+				m := MetricToFetch{}
+				m.Dimensions = s3m.Dimensions
+				m.Resource = bucket
+				mstatCopy := mstat
+				mstatCopy.Id = "s3" + strconv.Itoa(len(metrics))
+				m.MStat = mstatCopy
+				metrics = append(metrics, m)
 			}
-			m.Resource = bucket
-			mstatCopy := mstat
-			mstatCopy.Id = "s3" + strconv.Itoa(len(metrics))
-			m.MStat = mstatCopy
-			metrics = append(metrics, m)
 		}
 	}
 	return metrics, nil
@@ -124,11 +300,15 @@ func GetCloudwatchMetricList(cloudwatchSvc *cloudwatch.CloudWatch) ([]*cloudwatc
 		Namespace: &namespace,
 	}
 
-	listmetrics, err := cloudwatchSvc.ListMetrics(lmi)
+	listmetrics := make([]*cloudwatch.Metric, 0)
+	err := cloudwatchSvc.ListMetricsPages(lmi, func(page *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		listmetrics = append(listmetrics, page.Metrics...)
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
-	return listmetrics.Metrics, nil
+	return listmetrics, nil
 }
 
 func GetS3Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
@@ -143,7 +323,7 @@ func GetS3Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWat
 		return anodotMetrics, err
 	}
 
-	buckets, err := GetS3Buckets(session, listmetrics)
+	buckets, err := GetS3Buckets(session, listmetrics, resource)
 	if err != nil {
 		log.Printf("Could not describe S3 buckets: %v", err)
 		return anodotMetrics, err
@@ -156,6 +336,45 @@ func GetS3Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWat
 		return anodotMetrics, err
 	}
 
+	if len(metrics) > 0 {
+		cloudwatchMetrics, err := fetchS3CloudwatchMetrics(cloudWatchFetcher, metrics)
+		if err != nil {
+			log.Printf("Error during s3 metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+		anodotMetrics = append(anodotMetrics, cloudwatchMetrics...)
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		var usage []S3ObjectUsage
+		for _, cm := range resource.CustomMetrics {
+			if cm != "ObjectCount" && cm != "ObjectBytes" {
+				continue
+			}
+			// Reports are read once for both metrics
+			if usage == nil {
+				usage, err = GetS3ObjectUsage(session, resource)
+				if err != nil {
+					log.Printf("Could not read S3 inventory reports: %v", err)
+					return anodotMetrics, err
+				}
+				log.Printf("Got %d S3 object groups from reports", len(usage))
+			}
+			if cm == "ObjectCount" {
+				log.Printf("Processing S3 custom metric ObjectCount\n")
+				anodotMetrics = append(anodotMetrics, getS3ObjectUsageMetrics(usage, "object_count")...)
+			}
+			if cm == "ObjectBytes" {
+				log.Printf("Processing S3 custom metric ObjectBytes\n")
+				anodotMetrics = append(anodotMetrics, getS3ObjectUsageMetrics(usage, "object_bytes")...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
+
+func fetchS3CloudwatchMetrics(cloudWatchFetcher CloudWatchFetcher, metrics []MetricToFetch) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
 	dataInputs := NewGetMetricDataInput(metrics)
 
 	for _, mi := range dataInputs {
@@ -164,7 +383,6 @@ func GetS3Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWat
 
 	metricdataresults, err := cloudWatchFetcher.FetchMetrics(dataInputs)
 	if err != nil {
-		log.Printf("Error during s3 metrics processing: %v", err)
 		return anodotMetrics, err
 	}
 
@@ -178,6 +396,10 @@ func GetS3Metrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWat
 					for _, d := range m.Dimensions {
 						if d.Name == "StorageType" {
 							properties["storage_type"] = d.Value
+							properties["storage_class"] = s3StorageClass(d.Value)
+						}
+						if d.Name == "FilterId" {
+							properties["filter_id"] = escape(d.Value)
 						}
 					}
 				}
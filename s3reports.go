@@ -0,0 +1,613 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Objects are grouped by this many first key path segments by default
+const defaultReportPrefixDepth = 1
+
+// Prefix of objects stored at the root of a bucket
+const rootPrefix = "root"
+
+// Prefix of Storage Lens objects not covered by exported prefix records
+const otherPrefix = "other"
+
+// Only CSV reports are read, ORC and Parquet reports are rejected
+const csvReportFormat = "CSV"
+
+// Aggregated reports are cached under this checkpoint name followed by report path
+const s3ReportsCheckpoint = "S3Reports/"
+
+// reportSource reads S3 Inventory and Storage Lens reports from a bucket or from a local directory
+// with the same layout, so report processing can be run against downloaded reports.
+type reportSource interface {
+	// List returns keys under prefix
+	List(prefix string) ([]string, error)
+	Open(key string) (io.ReadCloser, error)
+}
+
+type s3ReportSource struct {
+	svc    *s3.S3
+	bucket string
+}
+
+func (s s3ReportSource) List(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+	err := s.svc.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			keys = append(keys, *o.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return keys, err
+	}
+	return keys, nil
+}
+
+func (s s3ReportSource) Open(key string) (io.ReadCloser, error) {
+	result, err := s.svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Body, nil
+}
+
+type localReportSource struct {
+	dir string
+}
+
+func (s localReportSource) List(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		key, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return keys, err
+	}
+	return keys, nil
+}
+
+func (s localReportSource) Open(key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+}
+
+// newReportSource returns source for s3://bucket location or a local directory
+func newReportSource(session *session.Session, location string) reportSource {
+	if strings.HasPrefix(location, "s3://") {
+		return s3ReportSource{
+			svc:    s3.New(session),
+			bucket: strings.TrimSuffix(strings.TrimPrefix(location, "s3://"), "/"),
+		}
+	}
+	return localReportSource{dir: location}
+}
+
+// ValidateReportFormat rejects configuration of reports which can not be read
+func ValidateReportFormat(resource *MonitoredResource) error {
+	if resource.ReportFormat != "" && resource.ReportFormat != csvReportFormat {
+		return fmt.Errorf("ReportFormat %s is not supported, S3 Inventory and Storage Lens reports must be exported as %s", resource.ReportFormat, csvReportFormat)
+	}
+	return nil
+}
+
+// InventoryManifest is manifest.json written by S3 Inventory with each report
+type InventoryManifest struct {
+	SourceBucket string `json:"sourceBucket"`
+	FileFormat   string `json:"fileFormat"`
+	FileSchema   string `json:"fileSchema"`
+	Files        []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// StorageLensManifest is manifest.json written by S3 Storage Lens with each metrics export
+type StorageLensManifest struct {
+	ReportFormat string `json:"reportFormat"`
+	ReportSchema string `json:"reportSchema"`
+	ReportFiles  []struct {
+		Key string `json:"key"`
+	} `json:"reportFiles"`
+}
+
+type S3ObjectGroup struct {
+	Region       string
+	BucketName   string
+	Prefix       string
+	StorageClass string
+	AgeBucket    string
+	Source       string
+}
+
+type S3ObjectUsage struct {
+	S3ObjectGroup
+	ObjectCount float64
+	ObjectBytes float64
+}
+
+// bucketLocator returns region of a bucket
+type bucketLocator func(bucket string) (string, error)
+
+// s3ObjectAggregator sums up objects of buckets located in region, reports cover buckets of all regions
+type s3ObjectAggregator struct {
+	depth     int
+	region    string
+	locate    bucketLocator
+	locations map[string]string
+	groups    map[S3ObjectGroup]*S3ObjectUsage
+}
+
+func newS3ObjectAggregator(depth int, region string, locate bucketLocator) *s3ObjectAggregator {
+	if depth <= 0 {
+		depth = defaultReportPrefixDepth
+	}
+	return &s3ObjectAggregator{
+		depth:     depth,
+		region:    region,
+		locate:    locate,
+		locations: make(map[string]string),
+		groups:    make(map[S3ObjectGroup]*S3ObjectUsage),
+	}
+}
+
+// inRegion tells whether bucket is located in aggregator region
+func (a *s3ObjectAggregator) inRegion(bucket string) (bool, error) {
+	location, ok := a.locations[bucket]
+	if !ok {
+		var err error
+		location, err = a.locate(bucket)
+		if err != nil {
+			return false, err
+		}
+		a.locations[bucket] = location
+	}
+	return location == a.region, nil
+}
+
+// keyPrefix returns first depth segments of object key, e.g. logs/2021/ for logs/2021/05/app.log and depth 2
+func keyPrefix(key string, depth int) string {
+	parts := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	// Last part is object name
+	parts = parts[:len(parts)-1]
+	if len(parts) == 0 {
+		return rootPrefix
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
+func (a *s3ObjectAggregator) add(g S3ObjectGroup, count, bytes float64) {
+	usage, ok := a.groups[g]
+	if !ok {
+		usage = &S3ObjectUsage{S3ObjectGroup: g}
+		a.groups[g] = usage
+	}
+	usage.ObjectCount += count
+	usage.ObjectBytes += bytes
+}
+
+func (a *s3ObjectAggregator) usage() []S3ObjectUsage {
+	usage := make([]S3ObjectUsage, 0)
+	for _, u := range a.groups {
+		usage = append(usage, *u)
+	}
+	return usage
+}
+
+// latestManifests returns the last manifest.json key of each report under prefix. Reports are written to
+// <report path>/<date>/manifest.json (e.g. <source-bucket>/<config-id>/<date>/ for inventory), so manifests
+// are grouped by report path and date folders are sorted.
+func latestManifests(source reportSource, prefix string) ([]string, error) {
+	keys, err := source.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]string)
+	for _, k := range keys {
+		if !strings.HasSuffix(k, "/manifest.json") {
+			continue
+		}
+		dateDir := path.Dir(k)
+		reportPath := path.Dir(dateDir)
+		if k > latest[reportPath] {
+			latest[reportPath] = k
+		}
+	}
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("no manifest.json found under %s", prefix)
+	}
+
+	manifests := make([]string, 0)
+	for _, k := range latest {
+		manifests = append(manifests, k)
+	}
+	sort.Strings(manifests)
+	return manifests, nil
+}
+
+func readManifest(source reportSource, key string, manifest interface{}) error {
+	f, err := source.Open(key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewDecoder(f).Decode(manifest)
+}
+
+// schemaColumns returns column index by name of "Bucket, Key, Size" like schema
+func schemaColumns(schema string) map[string]int {
+	columns := make(map[string]int)
+	for i, c := range strings.Split(schema, ",") {
+		columns[strings.TrimSpace(c)] = i
+	}
+	return columns
+}
+
+// readCsvReport calls fn for each record of a (gzipped) CSV report file
+func readCsvReport(source reportSource, key string, fn func(record []string)) error {
+	f, err := source.Open(key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(key, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(record)
+	}
+}
+
+// reportCache is aggregated usage of a report kept in lambda bucket. Reports are written daily,
+// so a report is streamed by the first lambda run after it is written and not by every hourly run.
+// Object age buckets are as of that first run.
+type reportCache struct {
+	ManifestKey string
+	Region      string
+	PrefixDepth int
+	Usage       []S3ObjectUsage
+}
+
+type reportReader func(source reportSource, manifestKey string, aggregator *s3ObjectAggregator) error
+
+// readReport returns aggregated usage of a report manifest in region, from cache when the manifest was read already.
+// Cache is kept per region and is used for reports read from S3 only.
+func readReport(source reportSource, manifestKey string, aggregator *s3ObjectAggregator, read reportReader) ([]S3ObjectUsage, error) {
+	region, depth := aggregator.region, aggregator.depth
+	_, cacheable := source.(s3ReportSource)
+	cacheName := s3ReportsCheckpoint + path.Dir(path.Dir(manifestKey))
+
+	if cacheable {
+		data, ok, err := GetCheckpointData(region, cacheName)
+		if err != nil {
+			log.Printf("Could not read S3 report cache %s: %v", cacheName, err)
+		}
+		cache := reportCache{}
+		if ok && json.Unmarshal(data, &cache) == nil && cache.ManifestKey == manifestKey && cache.Region == region && cache.PrefixDepth == depth {
+			log.Printf("Using cached S3 report %s", manifestKey)
+			return cache.Usage, nil
+		}
+	}
+
+	// Bucket locations are shared by reports
+	report := newS3ObjectAggregator(depth, region, aggregator.locate)
+	report.locations = aggregator.locations
+	if err := read(source, manifestKey, report); err != nil {
+		return nil, err
+	}
+	usage := report.usage()
+
+	if cacheable {
+		data, err := json.Marshal(reportCache{ManifestKey: manifestKey, Region: region, PrefixDepth: depth, Usage: usage})
+		if err == nil {
+			err = SaveCheckpointData(region, cacheName, data)
+		}
+		if err != nil {
+			log.Printf("Could not save S3 report cache %s: %v", cacheName, err)
+		}
+	}
+	return usage, nil
+}
+
+// readReports reads the latest report of each report path under prefix into aggregator
+func readReports(source reportSource, prefix string, aggregator *s3ObjectAggregator, read reportReader) error {
+	manifests, err := latestManifests(source, prefix)
+	if err != nil {
+		return err
+	}
+	for _, manifestKey := range manifests {
+		usage, err := readReport(source, manifestKey, aggregator, read)
+		if err != nil {
+			return err
+		}
+		for _, u := range usage {
+			aggregator.add(u.S3ObjectGroup, u.ObjectCount, u.ObjectBytes)
+		}
+	}
+	return nil
+}
+
+// readInventoryManifest aggregates objects of S3 Inventory report
+func readInventoryManifest(source reportSource, manifestKey string, aggregator *s3ObjectAggregator) error {
+	manifest := InventoryManifest{}
+	if err := readManifest(source, manifestKey, &manifest); err != nil {
+		return err
+	}
+	if manifest.FileFormat != csvReportFormat {
+		return fmt.Errorf("inventory %s is %s, only %s reports are supported", manifestKey, manifest.FileFormat, csvReportFormat)
+	}
+
+	// Inventory report lists objects of its source bucket only
+	inRegion, err := aggregator.inRegion(manifest.SourceBucket)
+	if err != nil {
+		return fmt.Errorf("could not get region of inventory source bucket %s: %v", manifest.SourceBucket, err)
+	}
+	if !inRegion {
+		return nil
+	}
+
+	columns := schemaColumns(manifest.FileSchema)
+	for _, c := range []string{"Bucket", "Key", "Size"} {
+		if _, ok := columns[c]; !ok {
+			return fmt.Errorf("inventory %s has no %s field", manifestKey, c)
+		}
+	}
+	storageClassColumn, hasStorageClass := columns["StorageClass"]
+	lastModifiedColumn, hasLastModified := columns["LastModifiedDate"]
+
+	log.Printf("Reading S3 inventory %s of %d files", manifestKey, len(manifest.Files))
+	for _, file := range manifest.Files {
+		err := readCsvReport(source, file.Key, func(record []string) {
+			if len(record) < len(columns) {
+				return
+			}
+			// Size is empty for delete markers
+			size, err := strconv.ParseFloat(record[columns["Size"]], 64)
+			if err != nil {
+				return
+			}
+			// Keys are URL encoded in inventory reports
+			key, err := url.QueryUnescape(record[columns["Key"]])
+			if err != nil {
+				key = record[columns["Key"]]
+			}
+
+			g := S3ObjectGroup{
+				Region:     aggregator.region,
+				BucketName: record[columns["Bucket"]],
+				Prefix:     keyPrefix(key, aggregator.depth),
+				Source:     "inventory",
+			}
+			if hasStorageClass {
+				g.StorageClass = record[storageClassColumn]
+			}
+			if hasLastModified {
+				if t, err := time.Parse(time.RFC3339, record[lastModifiedColumn]); err == nil {
+					g.AgeBucket = ageBucket(t)
+				}
+			}
+			aggregator.add(g, 1, size)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readStorageLensManifest aggregates bucket and prefix records of S3 Storage Lens metrics export.
+// Storage Lens has no object age, objects are grouped by bucket, prefix and storage class only.
+func readStorageLensManifest(source reportSource, manifestKey string, aggregator *s3ObjectAggregator) error {
+	manifest := StorageLensManifest{}
+	if err := readManifest(source, manifestKey, &manifest); err != nil {
+		return err
+	}
+	if manifest.ReportFormat != csvReportFormat {
+		return fmt.Errorf("storage lens export %s is %s, only %s exports are supported", manifestKey, manifest.ReportFormat, csvReportFormat)
+	}
+
+	columns := schemaColumns(manifest.ReportSchema)
+	for _, c := range []string{"aws_region", "storage_class", "record_type", "record_value", "bucket_name", "metric_name", "metric_value"} {
+		if _, ok := columns[c]; !ok {
+			return fmt.Errorf("storage lens export %s has no %s field", manifestKey, c)
+		}
+	}
+
+	// Bucket records hold totals of a bucket, prefix records its breakdown
+	totals := newS3ObjectAggregator(aggregator.depth, aggregator.region, aggregator.locate)
+
+	log.Printf("Reading S3 Storage Lens export %s of %d files", manifestKey, len(manifest.ReportFiles))
+	for _, file := range manifest.ReportFiles {
+		err := readCsvReport(source, file.Key, func(record []string) {
+			if len(record) < len(columns) || record[columns["aws_region"]] != aggregator.region {
+				return
+			}
+			value, err := strconv.ParseFloat(record[columns["metric_value"]], 64)
+			if err != nil {
+				return
+			}
+
+			g := S3ObjectGroup{
+				Region:       aggregator.region,
+				BucketName:   record[columns["bucket_name"]],
+				StorageClass: record[columns["storage_class"]],
+				Source:       "storage_lens",
+			}
+			target := aggregator
+			switch record[columns["record_type"]] {
+			case "BUCKET":
+				target = totals
+			case "PREFIX":
+				// Prefix records include their sub-prefixes, only records of the configured depth are summed up
+				prefix := strings.Trim(record[columns["record_value"]], "/")
+				if len(strings.Split(prefix, "/")) != aggregator.depth {
+					return
+				}
+				g.Prefix = prefix + "/"
+			default:
+				return
+			}
+
+			switch record[columns["metric_name"]] {
+			case "StorageBytes":
+				target.add(g, 0, value)
+			case "ObjectCount":
+				target.add(g, value, 0)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Storage Lens exports only prefixes above its size threshold. Objects at the root, at shallower depth
+	// and under smaller prefixes are reported as the rest of bucket total.
+	for g, total := range totals.groups {
+		count, bytes := total.ObjectCount, total.ObjectBytes
+		for pg, u := range aggregator.groups {
+			if pg.Source == g.Source && pg.BucketName == g.BucketName && pg.StorageClass == g.StorageClass {
+				count -= u.ObjectCount
+				bytes -= u.ObjectBytes
+			}
+		}
+		if count > 0 || bytes > 0 {
+			rest := g
+			rest.Prefix = otherPrefix
+			aggregator.add(rest, count, bytes)
+		}
+	}
+	return nil
+}
+
+// ReadInventoryReports aggregates objects of the latest S3 Inventory report of each source bucket and configuration under prefix
+func ReadInventoryReports(source reportSource, prefix string, aggregator *s3ObjectAggregator) error {
+	return readReports(source, prefix, aggregator, readInventoryManifest)
+}
+
+// ReadStorageLensReports aggregates the latest S3 Storage Lens metrics export of each configuration under prefix
+func ReadStorageLensReports(source reportSource, prefix string, aggregator *s3ObjectAggregator) error {
+	return readReports(source, prefix, aggregator, readStorageLensManifest)
+}
+
+// GetS3ObjectUsage reads configured S3 Inventory and Storage Lens reports from ReportLocation
+// and returns usage of buckets located in session region
+func GetS3ObjectUsage(session *session.Session, resource *MonitoredResource) ([]S3ObjectUsage, error) {
+	region := *session.Config.Region
+	svc := s3.New(session)
+	locate := func(bucket string) (string, error) {
+		return GetBucketRegion(svc, bucket)
+	}
+	aggregator := newS3ObjectAggregator(resource.ReportPrefixDepth, region, locate)
+	if resource.ReportLocation == "" {
+		return aggregator.usage(), fmt.Errorf("ReportLocation is not configured")
+	}
+	source := newReportSource(session, resource.ReportLocation)
+
+	if resource.InventoryPrefix != "" {
+		if err := ReadInventoryReports(source, resource.InventoryPrefix, aggregator); err != nil {
+			return aggregator.usage(), err
+		}
+	}
+	if resource.StorageLensPrefix != "" {
+		if err := ReadStorageLensReports(source, resource.StorageLensPrefix, aggregator); err != nil {
+			return aggregator.usage(), err
+		}
+	}
+	return aggregator.usage(), nil
+}
+
+func GetS3ObjectUsageMetricProperties(u S3ObjectUsage) map[string]string {
+	properties := map[string]string{
+		"service":          "s3",
+		"bucket_name":      u.BucketName,
+		"prefix":           escape(u.Prefix),
+		"storage_class":    u.StorageClass,
+		"age_bucket":       u.AgeBucket,
+		"report_source":    u.Source,
+		"region":           u.Region,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getS3ObjectUsageMetrics(usage []S3ObjectUsage, measurement string) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, u := range usage {
+		value := u.ObjectCount
+		if measurement == "object_bytes" {
+			value = u.ObjectBytes
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetS3ObjectUsageMetricProperties(u),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{measurement: value},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+)
+
+var testBucketRegions = map[string]string{
+	"source-bucket": "us-east-1",
+	"other-bucket":  "eu-west-1",
+}
+
+func testLocate(bucket string) (string, error) {
+	return testBucketRegions[bucket], nil
+}
+
+// usageByPrefix sums up usage of bucket by prefix over storage classes and age buckets
+func usageByPrefix(usage []S3ObjectUsage, bucket string) map[string]S3ObjectUsage {
+	prefixes := make(map[string]S3ObjectUsage)
+	for _, u := range usage {
+		if u.BucketName != bucket {
+			continue
+		}
+		p := prefixes[u.Prefix]
+		p.ObjectCount += u.ObjectCount
+		p.ObjectBytes += u.ObjectBytes
+		prefixes[u.Prefix] = p
+	}
+	return prefixes
+}
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key    string
+		depth  int
+		prefix string
+	}{
+		{"readme.txt", 1, rootPrefix},
+		{"/readme.txt", 2, rootPrefix},
+		{"logs/app.log", 1, "logs/"},
+		{"logs/app.log", 2, "logs/"},
+		{"logs/2021/05/app.log", 1, "logs/"},
+		{"logs/2021/05/app.log", 2, "logs/2021/"},
+		{"logs/2021/05/app.log", 3, "logs/2021/05/"},
+		{"logs/2021/05/app.log", 4, "logs/2021/05/"},
+	}
+	for _, test := range tests {
+		if prefix := keyPrefix(test.key, test.depth); prefix != test.prefix {
+			t.Errorf("keyPrefix(%q, %d) = %q, want %q", test.key, test.depth, prefix, test.prefix)
+		}
+	}
+}
+
+func TestReadInventoryReports(t *testing.T) {
+	aggregator := newS3ObjectAggregator(2, "us-east-1", testLocate)
+	err := ReadInventoryReports(localReportSource{dir: "testdata/s3reports"}, "inventory/", aggregator)
+	if err != nil {
+		t.Fatalf("ReadInventoryReports: %v", err)
+	}
+	usage := aggregator.usage()
+
+	for _, u := range usage {
+		if u.Region != "us-east-1" || u.Source != "inventory" {
+			t.Errorf("unexpected region %s or source %s of %+v", u.Region, u.Source, u.S3ObjectGroup)
+		}
+	}
+	if other := usageByPrefix(usage, "other-bucket"); len(other) != 0 {
+		t.Errorf("objects of bucket in other region are reported: %+v", other)
+	}
+
+	// Older report and delete marker are skipped
+	want := map[string]S3ObjectUsage{
+		"logs/2021/": S3ObjectUsage{ObjectCount: 2, ObjectBytes: 300},
+		"logs/":      S3ObjectUsage{ObjectCount: 1, ObjectBytes: 50},
+		rootPrefix:   S3ObjectUsage{ObjectCount: 1, ObjectBytes: 10},
+	}
+	got := usageByPrefix(usage, "source-bucket")
+	if len(got) != len(want) {
+		t.Errorf("got prefixes %+v, want %+v", got, want)
+	}
+	for prefix, w := range want {
+		if g := got[prefix]; g.ObjectCount != w.ObjectCount || g.ObjectBytes != w.ObjectBytes {
+			t.Errorf("prefix %s: got %v objects of %v bytes, want %v objects of %v bytes", prefix, g.ObjectCount, g.ObjectBytes, w.ObjectCount, w.ObjectBytes)
+		}
+	}
+}
+
+func TestReadStorageLensReports(t *testing.T) {
+	aggregator := newS3ObjectAggregator(1, "us-east-1", testLocate)
+	err := ReadStorageLensReports(localReportSource{dir: "testdata/s3reports"}, "lens/", aggregator)
+	if err != nil {
+		t.Fatalf("ReadStorageLensReports: %v", err)
+	}
+	usage := aggregator.usage()
+
+	if west := usageByPrefix(usage, "west-bucket"); len(west) != 0 {
+		t.Errorf("records of other aws_region are reported: %+v", west)
+	}
+
+	// Deeper prefix records are skipped, the rest of bucket total is reported as other
+	want := map[string]S3ObjectUsage{
+		"logs/":     S3ObjectUsage{ObjectCount: 6, ObjectBytes: 600},
+		"data/":     S3ObjectUsage{ObjectCount: 3, ObjectBytes: 300},
+		otherPrefix: S3ObjectUsage{ObjectCount: 1, ObjectBytes: 100},
+	}
+	got := usageByPrefix(usage, "lens-bucket")
+	if len(got) != len(want) {
+		t.Errorf("got prefixes %+v, want %+v", got, want)
+	}
+	for prefix, w := range want {
+		if g := got[prefix]; g.ObjectCount != w.ObjectCount || g.ObjectBytes != w.ObjectBytes {
+			t.Errorf("prefix %s: got %v objects of %v bytes, want %v objects of %v bytes", prefix, g.ObjectCount, g.ObjectBytes, w.ObjectCount, w.ObjectBytes)
+		}
+	}
+}
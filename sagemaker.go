@@ -0,0 +1,363 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/sagemaker"
+)
+
+type SageMakerResource struct {
+	ResourceType  string
+	Name          string
+	Variant       string
+	InstanceType  string
+	InstanceCount int64
+	Status        string
+	Region        string
+}
+
+func getSageMakerEndpoints(svc *sagemaker.SageMaker, region string) ([]SageMakerResource, error) {
+	endpoints := make([]SageMakerResource, 0)
+	names := make([]*string, 0)
+
+	err := svc.ListEndpointsPages(&sagemaker.ListEndpointsInput{}, func(page *sagemaker.ListEndpointsOutput, lastPage bool) bool {
+		for _, e := range page.Endpoints {
+			names = append(names, e.EndpointName)
+		}
+		return true
+	})
+	if err != nil {
+		return endpoints, err
+	}
+
+	for _, name := range names {
+		endpoint, err := svc.DescribeEndpoint(&sagemaker.DescribeEndpointInput{
+			EndpointName: name,
+		})
+		if err != nil {
+			return endpoints, err
+		}
+
+		// Instance type of a variant is known from endpoint config only
+		instanceTypes := make(map[string]string)
+		config, err := svc.DescribeEndpointConfig(&sagemaker.DescribeEndpointConfigInput{
+			EndpointConfigName: endpoint.EndpointConfigName,
+		})
+		if err != nil {
+			return endpoints, err
+		}
+		for _, v := range config.ProductionVariants {
+			instanceTypes[*v.VariantName] = aws.StringValue(v.InstanceType)
+		}
+
+		for _, v := range endpoint.ProductionVariants {
+			endpoints = append(endpoints, SageMakerResource{
+				ResourceType:  "endpoint",
+				Name:          *name,
+				Variant:       *v.VariantName,
+				InstanceType:  instanceTypes[*v.VariantName],
+				InstanceCount: aws.Int64Value(v.CurrentInstanceCount),
+				Status:        aws.StringValue(endpoint.EndpointStatus),
+				Region:        region,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+func getSageMakerNotebooks(svc *sagemaker.SageMaker, region string) ([]SageMakerResource, error) {
+	notebooks := make([]SageMakerResource, 0)
+	input := &sagemaker.ListNotebookInstancesInput{
+		StatusEquals: aws.String(sagemaker.NotebookInstanceStatusInService),
+	}
+
+	err := svc.ListNotebookInstancesPages(input, func(page *sagemaker.ListNotebookInstancesOutput, lastPage bool) bool {
+		for _, n := range page.NotebookInstances {
+			notebooks = append(notebooks, SageMakerResource{
+				ResourceType:  "notebook",
+				Name:          *n.NotebookInstanceName,
+				InstanceType:  aws.StringValue(n.InstanceType),
+				InstanceCount: 1,
+				Status:        aws.StringValue(n.NotebookInstanceStatus),
+				Region:        region,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return notebooks, err
+	}
+	return notebooks, nil
+}
+
+// Jobs are active in the window when they are in progress or were modified (e.g. completed) after window start
+func getSageMakerTrainingJobs(svc *sagemaker.SageMaker, region string, since time.Time) ([]SageMakerResource, error) {
+	jobs := make([]SageMakerResource, 0)
+	names := make([]*string, 0)
+
+	// Long running jobs may be not modified within the window, in progress ones are listed separately
+	inputs := []*sagemaker.ListTrainingJobsInput{
+		&sagemaker.ListTrainingJobsInput{
+			LastModifiedTimeAfter: aws.Time(since),
+		},
+		&sagemaker.ListTrainingJobsInput{
+			StatusEquals: aws.String(sagemaker.TrainingJobStatusInProgress),
+		},
+	}
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		err := svc.ListTrainingJobsPages(input, func(page *sagemaker.ListTrainingJobsOutput, lastPage bool) bool {
+			for _, j := range page.TrainingJobSummaries {
+				if !seen[*j.TrainingJobName] {
+					seen[*j.TrainingJobName] = true
+					names = append(names, j.TrainingJobName)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return jobs, err
+		}
+	}
+
+	for _, name := range names {
+		job, err := svc.DescribeTrainingJob(&sagemaker.DescribeTrainingJobInput{
+			TrainingJobName: name,
+		})
+		if err != nil {
+			return jobs, err
+		}
+		if job.ResourceConfig == nil {
+			continue
+		}
+		jobs = append(jobs, SageMakerResource{
+			ResourceType:  "training_job",
+			Name:          *name,
+			InstanceType:  aws.StringValue(job.ResourceConfig.InstanceType),
+			InstanceCount: aws.Int64Value(job.ResourceConfig.InstanceCount),
+			Status:        aws.StringValue(job.TrainingJobStatus),
+			Region:        region,
+		})
+	}
+	return jobs, nil
+}
+
+func getSageMakerProcessingJobs(svc *sagemaker.SageMaker, region string, since time.Time) ([]SageMakerResource, error) {
+	jobs := make([]SageMakerResource, 0)
+	names := make([]*string, 0)
+
+	inputs := []*sagemaker.ListProcessingJobsInput{
+		&sagemaker.ListProcessingJobsInput{
+			LastModifiedTimeAfter: aws.Time(since),
+		},
+		&sagemaker.ListProcessingJobsInput{
+			StatusEquals: aws.String(sagemaker.ProcessingJobStatusInProgress),
+		},
+	}
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		err := svc.ListProcessingJobsPages(input, func(page *sagemaker.ListProcessingJobsOutput, lastPage bool) bool {
+			for _, j := range page.ProcessingJobSummaries {
+				if !seen[*j.ProcessingJobName] {
+					seen[*j.ProcessingJobName] = true
+					names = append(names, j.ProcessingJobName)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return jobs, err
+		}
+	}
+
+	for _, name := range names {
+		job, err := svc.DescribeProcessingJob(&sagemaker.DescribeProcessingJobInput{
+			ProcessingJobName: name,
+		})
+		if err != nil {
+			return jobs, err
+		}
+		if job.ProcessingResources == nil || job.ProcessingResources.ClusterConfig == nil {
+			continue
+		}
+		jobs = append(jobs, SageMakerResource{
+			ResourceType:  "processing_job",
+			Name:          *name,
+			InstanceType:  aws.StringValue(job.ProcessingResources.ClusterConfig.InstanceType),
+			InstanceCount: aws.Int64Value(job.ProcessingResources.ClusterConfig.InstanceCount),
+			Status:        aws.StringValue(job.ProcessingJobStatus),
+			Region:        region,
+		})
+	}
+	return jobs, nil
+}
+
+func GetSageMakerResources(session *session.Session, resource *MonitoredResource) ([]SageMakerResource, error) {
+	region := *session.Config.Region
+	svc := sagemaker.New(session)
+	since := time.Now().Add(-offset)
+
+	instances, err := getSageMakerEndpoints(svc, region)
+	if err != nil {
+		return instances, err
+	}
+
+	notebooks, err := getSageMakerNotebooks(svc, region)
+	if err != nil {
+		return instances, err
+	}
+	instances = append(instances, notebooks...)
+
+	trainingJobs, err := getSageMakerTrainingJobs(svc, region, since)
+	if err != nil {
+		return instances, err
+	}
+	instances = append(instances, trainingJobs...)
+
+	processingJobs, err := getSageMakerProcessingJobs(svc, region, since)
+	if err != nil {
+		return instances, err
+	}
+	instances = append(instances, processingJobs...)
+	return instances, nil
+}
+
+func GetSageMakerDimensions() []string {
+	return []string{
+		"service",
+		"resource_type",
+		"resource_name",
+		"variant",
+		"instance_type",
+		"status",
+		"region",
+		"anodot-collector",
+	}
+}
+
+func GetSageMakerCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "InstanceCount",
+			Alias:      "InstanceCount",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetSageMakerMetricProperties(i SageMakerResource) map[string]string {
+	properties := map[string]string{
+		"service":          "sagemaker",
+		"resource_type":    i.ResourceType,
+		"resource_name":    escape(i.Name),
+		"variant":          escape(i.Variant),
+		"instance_type":    i.InstanceType,
+		"status":           i.Status,
+		"region":           i.Region,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+// Invocations, ModelLatency and other AWS/SageMaker metrics are published per endpoint variant
+func GetSageMakerCloudwatchMetrics(resource *MonitoredResource, instances []SageMakerResource) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+	for _, mstat := range resource.Metrics {
+		for _, i := range instances {
+			if i.ResourceType != "endpoint" {
+				continue
+			}
+			m := MetricToFetch{}
+			m.Dimensions = []Dimension{
+				Dimension{
+					Name:  "EndpointName",
+					Value: i.Name,
+				},
+				Dimension{
+					Name:  "VariantName",
+					Value: i.Variant,
+				},
+			}
+			m.Resource = i
+			mstatCopy := mstat
+			mstatCopy.Id = "sagemaker" + strconv.Itoa(len(metrics))
+			m.MStat = mstatCopy
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func getSageMakerInstanceCount(instances []SageMakerResource) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, i := range instances {
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetSageMakerMetricProperties(i),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{"InstanceCount": float64(i.InstanceCount)},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetSageMakerMetrics30(ses *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	anodotMetrics := make([]metrics3.AnodotMetrics30, 0)
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+
+	instances, err := GetSageMakerResources(ses, resource)
+	if err != nil {
+		log.Printf("Cloud not describe SageMaker resources: %v", err)
+		return anodotMetrics, err
+	}
+	log.Printf("Found %d SageMaker endpoint variants, notebooks and jobs", len(instances))
+
+	metrics, err := GetSageMakerCloudwatchMetrics(resource, instances)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return anodotMetrics, err
+	}
+
+	if len(metrics) > 0 {
+		metricdatainput := NewGetMetricDataInput(metrics)
+		metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+		if err != nil {
+			log.Printf("Error during SageMaker metrics processing: %v", err)
+			return anodotMetrics, err
+		}
+
+		for _, m := range metrics {
+			for _, mr := range metricdataresults {
+				if *mr.Id == m.MStat.Id {
+					i := m.Resource.(SageMakerResource)
+					anodot_sagemaker_metrics := GetAnodotMetric30(m.MStat.Name, mr.Timestamps, mr.Values, GetSageMakerMetricProperties(i))
+					anodotMetrics = append(anodotMetrics, anodot_sagemaker_metrics...)
+				}
+			}
+		}
+	}
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			if cm == "InstanceCount" {
+				log.Printf("Processing SageMaker custom metric InstanceCount\n")
+				anodotMetrics = append(anodotMetrics, getSageMakerInstanceCount(instances)...)
+			}
+		}
+	}
+	return anodotMetrics, nil
+}
@@ -78,9 +78,9 @@ func GetCustomMetricsAndDimensions(servicName string, resource *MonitoredResourc
 	case "EBS":
 		return GetEBSCustomMetrics(), GetEBSDimensions(resource)
 	case "ELB":
-		return emptyCm, GetELBDimensions(resource)
+		return GetELBCustomMetrics(), GetELBDimensions(resource)
 	case "S3":
-		return emptyCm, GetS3Dimensions()
+		return GetS3CustomMetrics(), GetS3Dimensions()
 	case "Cloudfront":
 		return emptyCm, GetCloudfrontDimensions()
 	case "NatGateway":
@@ -93,6 +93,46 @@ func GetCustomMetricsAndDimensions(servicName string, resource *MonitoredResourc
 		return emptyCm, GetStreamDimensions()
 	case "ElastiCache":
 		return GetElasticacheCustomMetrics(), GetElasticacheDimensions()
+	case "APIGateway":
+		return emptyCm, GetApiGatewayDimensions(resource)
+	case "Redshift":
+		return GetRedshiftCustomMetrics(), GetRedshiftDimensions(resource)
+	case "OpenSearch":
+		return GetOpenSearchCustomMetrics(), GetOpenSearchDimensions()
+	case "Firehose":
+		return emptyCm, GetStreamDimensions()
+	case "MSK":
+		return GetMskCustomMetrics(), GetMskDimensions()
+	case "Network":
+		return GetNetworkCustomMetrics(), GetNetworkDimensions(resource)
+	case "EBSSnapshots":
+		return GetEBSSnapshotsCustomMetrics(), GetEBSSnapshotsDimensions(resource)
+	case "AutoScaling":
+		return GetAutoScalingCustomMetrics(), GetAutoScalingDimensions(resource)
+	case "ServiceQuotas":
+		return GetServiceQuotasCustomMetrics(), GetServiceQuotasDimensions()
+	case "CostExplorer":
+		return GetCostExplorerSchemaMetrics(resource), GetCostExplorerDimensions()
+	case "Reservations":
+		return GetReservationsCustomMetrics(), GetReservationsDimensions()
+	case "CloudWatchLogs":
+		return GetCloudWatchLogsCustomMetrics(), GetCloudWatchLogsDimensions()
+	case "Generic":
+		return emptyCm, GetGenericDimensions(resource)
+	case "DataPipeline":
+		return GetDataPipelineCustomMetrics(), GetDataPipelineDimensions(resource)
+	case "SageMaker":
+		return GetSageMakerCustomMetrics(), GetSageMakerDimensions()
+	case "FSx":
+		return GetFSxCustomMetrics(), GetFSxDimensions(resource)
+	case "Backup":
+		return GetBackupCustomMetrics(), GetBackupDimensions()
+	case "Route53":
+		return GetRoute53CustomMetrics(), GetRoute53Dimensions()
+	case "GlobalAccelerator":
+		return GetGlobalAcceleratorCustomMetrics(), GetGlobalAcceleratorDimensions()
+	case "Waste":
+		return GetWasteCustomMetrics(), GetWasteDimensions()
 	default:
 		return emptyCm, emptyD
 	}
@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+# Converts AWS EC2 price list offer file (CSV) of a region into on-demand price table used by lambda:
+# region,instance_type,operating_system,tenancy,price_per_hour
+#
+# curl -s https://pricing.us-east-1.amazonaws.com/offers/v1.0/aws/AmazonEC2/current/us-east-1/index.csv | python scripts/ec2_price_table.py us-east-1 >> ec2_prices.csv
+import csv
+import sys
+
+# Offer file starts with metadata lines before the header
+METADATA_LINES = 5
+
+
+def main():
+    region = sys.argv[1]
+    lines = iter(sys.stdin)
+    for _ in range(METADATA_LINES):
+        next(lines)
+
+    writer = csv.writer(sys.stdout)
+    seen = set()
+    for row in csv.DictReader(lines):
+        if row.get("TermType") != "OnDemand" or row.get("Unit") != "Hrs":
+            continue
+        if row.get("CapacityStatus") != "Used" or row.get("Pre Installed S/W") != "NA":
+            continue
+        if row.get("License Model") == "Bring your own license" or not row.get("Instance Type"):
+            continue
+
+        key = (row["Instance Type"], row["Operating System"], row["Tenancy"])
+        if key in seen:
+            continue
+        seen.add(key)
+        writer.writerow([region, row["Instance Type"], row["Operating System"], row["Tenancy"], row["PricePerUnit"]])
+
+
+if __name__ == "__main__":
+    main()
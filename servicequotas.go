@@ -0,0 +1,304 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+)
+
+// Instance families counted by "Running On-Demand Standard instances" quota.
+// Families with the same first letter (inf, dl, trn, hpc, mac) have quotas of their own.
+var standardInstanceFamilies = map[string]bool{
+	"a":  true,
+	"c":  true,
+	"d":  true,
+	"h":  true,
+	"i":  true,
+	"im": true,
+	"is": true,
+	"m":  true,
+	"r":  true,
+	"t":  true,
+	"z":  true,
+}
+
+// quotaInstanceClass returns letters before the generation digit of instance type, e.g. inf for inf1.xlarge
+func quotaInstanceClass(instanceType string) string {
+	family := strings.ToLower(instanceType)
+	if i := strings.IndexAny(family, "0123456789.-"); i >= 0 {
+		family = family[:i]
+	}
+	return family
+}
+
+// QuotaUsageFunc returns current usage of a quota keyed by availability zone,
+// empty key is used for regional quotas
+type QuotaUsageFunc func(*session.Session) (map[string]float64, error)
+
+type QuotaDefinition struct {
+	ServiceCode string
+	QuotaCode   string
+	Usage       QuotaUsageFunc
+}
+
+type QuotaUsage struct {
+	ServiceCode      string
+	QuotaCode        string
+	QuotaName        string
+	AvailabilityZone string
+	Limit            float64
+	Used             float64
+	Region           string
+}
+
+func GetQuotaDefinitions() []QuotaDefinition {
+	return []QuotaDefinition{
+		{
+			// Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances, in vCPUs
+			ServiceCode: "ec2",
+			QuotaCode:   "L-1216C47A",
+			Usage:       getOnDemandStandardVCpuUsage,
+		},
+		{
+			// EC2-VPC Elastic IPs
+			ServiceCode: "ec2",
+			QuotaCode:   "L-0263D0A3",
+			Usage:       getElasticIpUsage,
+		},
+		{
+			// NAT gateways per Availability Zone
+			ServiceCode: "vpc",
+			QuotaCode:   "L-FE5A380F",
+			Usage:       getNatGatewayPerAzUsage,
+		},
+	}
+}
+
+func getOnDemandStandardVCpuUsage(session *session.Session) (map[string]float64, error) {
+	instanceFetcher := CreateEC2Fetcher(session)
+	instances, err := instanceFetcher.GetInstances(&MonitoredResource{})
+	if err != nil {
+		return nil, err
+	}
+
+	var vcpus float64
+	for _, i := range instances {
+		// Quota counts running and pending instances only
+		if i.Lifecycle != "normal" || (i.State != "running" && i.State != "pending") {
+			continue
+		}
+		if standardInstanceFamilies[quotaInstanceClass(i.InstanceType)] {
+			vcpus += float64(i.CoreCount * i.ThreadsPerCore)
+		}
+	}
+	return map[string]float64{"": vcpus}, nil
+}
+
+func getElasticIpUsage(session *session.Session) (map[string]float64, error) {
+	ips, err := DescribeElasticIps(ec2.New(session), *session.Config.Region)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]float64{"": float64(len(ips))}, nil
+}
+
+func getNatGatewayPerAzUsage(session *session.Session) (map[string]float64, error) {
+	ec2svc := ec2.New(session)
+	usage := make(map[string]float64)
+	subnetIds := make([]*string, 0)
+
+	input := &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("state"),
+				Values: []*string{aws.String("pending"), aws.String("available")},
+			},
+		},
+	}
+	err := ec2svc.DescribeNatGatewaysPages(input, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, g := range page.NatGateways {
+			subnetIds = append(subnetIds, g.SubnetId)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(subnetIds) == 0 {
+		return usage, nil
+	}
+
+	subnetsAz, err := GetSubnetsAz(ec2svc, subnetIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range subnetIds {
+		usage[subnetsAz[*s]]++
+	}
+	return usage, nil
+}
+
+// GetAppliedQuota returns applied quota value, or AWS default one when quota has never been changed
+func GetAppliedQuota(svc *servicequotas.ServiceQuotas, serviceCode, quotaCode string) (*servicequotas.ServiceQuota, error) {
+	output, err := svc.GetServiceQuota(&servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+	if err == nil {
+		return output.Quota, nil
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != servicequotas.ErrCodeNoSuchResourceException {
+		return nil, err
+	}
+
+	defaultOutput, err := svc.GetAWSDefaultServiceQuota(&servicequotas.GetAWSDefaultServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return defaultOutput.Quota, nil
+}
+
+func GetQuotaUsages(session *session.Session) ([]QuotaUsage, error) {
+	usages := make([]QuotaUsage, 0)
+	region := session.Config.Region
+	svc := servicequotas.New(session)
+
+	for _, qd := range GetQuotaDefinitions() {
+		quota, err := GetAppliedQuota(svc, qd.ServiceCode, qd.QuotaCode)
+		if err != nil {
+			return usages, err
+		}
+
+		used, err := qd.Usage(session)
+		if err != nil {
+			return usages, err
+		}
+
+		// Per AZ quota with nothing used still has to be reported for the region
+		if len(used) == 0 {
+			used[""] = 0
+		}
+
+		for az, u := range used {
+			usages = append(usages, QuotaUsage{
+				ServiceCode:      qd.ServiceCode,
+				QuotaCode:        qd.QuotaCode,
+				QuotaName:        aws.StringValue(quota.QuotaName),
+				AvailabilityZone: az,
+				Limit:            aws.Float64Value(quota.Value),
+				Used:             u,
+				Region:           *region,
+			})
+		}
+	}
+	return usages, nil
+}
+
+func GetServiceQuotasDimensions() []string {
+	return []string{
+		"service",
+		"quota_service",
+		"quota_code",
+		"quota_name",
+		"availability_zone",
+		"region",
+		"anodot-collector",
+	}
+}
+
+func GetServiceQuotasCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "quota_limit",
+			Alias:      "quota_limit",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "quota_used",
+			Alias:      "quota_used",
+			TargetType: "average",
+		},
+		CustomMetricDefinition{
+			Name:       "quota_utilization_pct",
+			Alias:      "quota_utilization_pct",
+			TargetType: "average",
+		},
+	}
+}
+
+func GetServiceQuotaMetricProperties(q QuotaUsage) map[string]string {
+	properties := map[string]string{
+		"service":           "servicequotas",
+		"quota_service":     q.ServiceCode,
+		"quota_code":        q.QuotaCode,
+		"quota_name":        escape(q.QuotaName),
+		"availability_zone": q.AvailabilityZone,
+		"region":            q.Region,
+		"anodot-collector":  "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func getServiceQuotaMetric(usages []QuotaUsage, what string) []metrics3.AnodotMetrics30 {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	for _, q := range usages {
+		var value float64
+		switch what {
+		case "quota_limit":
+			value = q.Limit
+		case "quota_used":
+			value = q.Used
+		case "quota_utilization_pct":
+			if q.Limit == 0 {
+				continue
+			}
+			value = q.Used / q.Limit * 100
+		}
+		metric := metrics3.AnodotMetrics30{
+			Dimensions:   GetServiceQuotaMetricProperties(q),
+			Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+			Measurements: map[string]float64{what: value},
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func GetServiceQuotasMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+	usages, err := GetQuotaUsages(session)
+	if err != nil {
+		log.Printf("Cloud not get service quotas usage %v", err)
+		return metrics, err
+	}
+	log.Printf("Got %d service quotas to process", len(usages))
+
+	if len(resource.CustomMetrics) > 0 {
+		for _, cm := range resource.CustomMetrics {
+			switch cm {
+			case "quota_limit", "quota_used", "quota_utilization_pct":
+				log.Printf("Processing ServiceQuotas custom metric %s\n", cm)
+				metrics = append(metrics, getServiceQuotaMetric(usages, cm)...)
+			}
+		}
+	}
+	return metrics, nil
+}
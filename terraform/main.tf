@@ -45,7 +45,51 @@ resource "aws_iam_policy" "usage_lambda_policy" {
             "s3:ListAllMyBuckets",
             "s3:ListBucket",
             "s3:GetObject",
-            "dynamodb:ListTables"
+            "dynamodb:ListTables",
+            "apigateway:GET",
+            "redshift:DescribeClusters",
+            "es:ListDomainNames",
+            "es:DescribeElasticsearchDomains",
+            "firehose:ListDeliveryStreams",
+            "kafka:ListClusters",
+            "kafka:ListNodes",
+            "autoscaling:DescribeAutoScalingGroups",
+            "servicequotas:GetServiceQuota",
+            "servicequotas:GetAWSDefaultServiceQuota",
+            "ce:GetCostAndUsage",
+            "s3:PutObject",
+            "ce:GetReservationUtilization",
+            "ce:GetReservationCoverage",
+            "ce:GetSavingsPlansUtilizationDetails",
+            "ce:GetSavingsPlansCoverage",
+            "logs:DescribeLogGroups",
+            "states:ListStateMachines",
+            "states:ListTagsForResource",
+            "glue:GetJobs",
+            "glue:GetJobRuns",
+            "glue:GetTags",
+            "athena:ListWorkGroups",
+            "athena:ListTagsForResource",
+            "sagemaker:ListEndpoints",
+            "sagemaker:DescribeEndpoint",
+            "sagemaker:DescribeEndpointConfig",
+            "sagemaker:ListNotebookInstances",
+            "sagemaker:ListTrainingJobs",
+            "sagemaker:DescribeTrainingJob",
+            "sagemaker:ListProcessingJobs",
+            "sagemaker:DescribeProcessingJob",
+            "fsx:DescribeFileSystems",
+            "backup:ListBackupVaults",
+            "backup:ListRecoveryPointsByBackupVault",
+            "route53:ListHostedZones",
+            "route53:ListQueryLoggingConfigs",
+            "route53:ListHealthChecks",
+            "globalaccelerator:ListAccelerators",
+            "ec2:DescribeSpotPriceHistory",
+            "elasticloadbalancing:DescribeTargetGroups",
+            "s3:GetBucketLocation",
+            "s3:GetMetricsConfiguration",
+            "s3:PutMetricsConfiguration"
           ],
           Effect: "Allow",
           Resource: "*"
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/anodot/anodot-common/pkg/metrics3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Resources are checked for activity within this many last hours by default
+const defaultIdleHours = 24
+
+// NAT gateway which sent to and received from destinations less bytes than this within the idle window is reported as idle
+const natIdleBytes = 1024 * 1024
+
+const (
+	unattachedVolumeReason = "unattached_volume"
+	lowNatTrafficReason    = "low_nat_traffic"
+	noRequestsReason       = "no_requests"
+	noCommandsReason       = "no_commands"
+	unassociatedEipReason  = "unassociated_eip"
+)
+
+type IdleResource struct {
+	ResourceType string
+	ResourceId   string
+	Reason       string
+	Region       string
+}
+
+// idleCheck is an activity metric of a resource. Resource is idle when sum of all its activity metrics
+// within the window is not above threshold
+type idleCheck struct {
+	resource  IdleResource
+	threshold float64
+}
+
+func (c idleCheck) key() string {
+	return c.resource.ResourceType + "/" + c.resource.ResourceId
+}
+
+// Commands are counted for ElastiCache, clients with connection pools do not open new connections while busy
+var elasticacheCommandMetrics = map[string][]string{
+	"redis":     []string{"GetTypeCmds", "SetTypeCmds"},
+	"memcached": []string{"CmdGet", "CmdSet"},
+}
+
+// Hourly sums are fetched for the whole idle window and added up
+func newIdleMetricStat(name, namespace, unit string) MetricStat {
+	return MetricStat{
+		Name:      name,
+		Namespace: namespace,
+		Period:    "3600",
+		Unit:      unit,
+		Stat:      "Sum",
+	}
+}
+
+func getNatGatewayIdleChecks(session *session.Session) ([]MetricToFetch, error) {
+	region := *session.Config.Region
+	metrics := make([]MetricToFetch, 0)
+
+	input := &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("state"),
+				Values: []*string{aws.String("available")},
+			},
+		},
+	}
+	err := ec2.New(session).DescribeNatGatewaysPages(input, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, g := range page.NatGateways {
+			// Both uploads and downloads through the gateway are counted
+			for _, name := range []string{"BytesOutToDestination", "BytesInFromDestination"} {
+				metrics = append(metrics, MetricToFetch{
+					Dimensions: []Dimension{
+						Dimension{
+							Name:  "NatGatewayId",
+							Value: *g.NatGatewayId,
+						},
+					},
+					MStat: newIdleMetricStat(name, "AWS/NATGateway", "Bytes"),
+					Resource: idleCheck{
+						resource: IdleResource{
+							ResourceType: "nat_gateway",
+							ResourceId:   *g.NatGatewayId,
+							Reason:       lowNatTrafficReason,
+							Region:       region,
+						},
+						threshold: natIdleBytes,
+					},
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return metrics, err
+	}
+	return metrics, nil
+}
+
+func getLoadBalancerIdleChecks(session *session.Session, resource *MonitoredResource) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+	elbs, err := GetLoadBalancers(session, resource)
+	if err != nil {
+		return metrics, err
+	}
+
+	for _, lb := range elbs {
+		var mstat MetricStat
+		var dims []Dimension
+		switch lb.Type {
+		case "classic":
+			mstat = newIdleMetricStat("RequestCount", "AWS/ELB", "Count")
+			dims = []Dimension{Dimension{Name: "LoadBalancerName", Value: lb.Name}}
+		case "application":
+			mstat = newIdleMetricStat("RequestCount", "AWS/ApplicationELB", "Count")
+			dims = []Dimension{Dimension{Name: "LoadBalancer", Value: loadBalancerArnSuffix(lb.Arn)}}
+		case "network":
+			// Network load balancers have no requests, new flows are checked instead
+			mstat = newIdleMetricStat("NewFlowCount", "AWS/NetworkELB", "Count")
+			dims = []Dimension{Dimension{Name: "LoadBalancer", Value: loadBalancerArnSuffix(lb.Arn)}}
+		default:
+			continue
+		}
+
+		metrics = append(metrics, MetricToFetch{
+			Dimensions: dims,
+			MStat:      mstat,
+			Resource: idleCheck{
+				resource: IdleResource{
+					ResourceType: "load_balancer_" + lb.Type,
+					ResourceId:   lb.Name,
+					Reason:       noRequestsReason,
+					Region:       lb.Region,
+				},
+			},
+		})
+	}
+	return metrics, nil
+}
+
+func getElasticacheIdleChecks(session *session.Session) ([]MetricToFetch, error) {
+	metrics := make([]MetricToFetch, 0)
+	clusters, err := GetCacheClusters(session)
+	if err != nil {
+		return metrics, err
+	}
+
+	for _, c := range clusters {
+		for _, name := range elasticacheCommandMetrics[c.Engine] {
+			metrics = append(metrics, MetricToFetch{
+				Dimensions: []Dimension{
+					Dimension{
+						Name:  "CacheClusterId",
+						Value: c.CacheClusterId,
+					},
+				},
+				MStat: newIdleMetricStat(name, "AWS/ElastiCache", "Count"),
+				Resource: idleCheck{
+					resource: IdleResource{
+						ResourceType: "elasticache_" + c.Engine,
+						ResourceId:   c.CacheClusterId,
+						Reason:       noCommandsReason,
+						Region:       c.Region,
+					},
+				},
+			})
+		}
+	}
+	return metrics, nil
+}
+
+// GetIdleByMetrics fetches activity metrics of resources for the window.
+// Resources without data points are idle as well, CloudWatch does not publish zero requests
+func GetIdleByMetrics(cloudwatchSvc *cloudwatch.CloudWatch, metrics []MetricToFetch, window time.Duration) ([]IdleResource, error) {
+	idle := make([]IdleResource, 0)
+	if len(metrics) == 0 {
+		return idle, nil
+	}
+
+	for i := range metrics {
+		metrics[i].MStat.Id = "waste" + strconv.Itoa(i)
+	}
+
+	cloudWatchFetcher := CloudWatchFetcher{
+		cloudwatchSvc: cloudwatchSvc,
+	}
+	metricdatainput := NewGetMetricDataInput(metrics)
+	for _, di := range metricdatainput {
+		di.SetStartTime(di.EndTime.Add(-window))
+	}
+	metricdataresults, err := cloudWatchFetcher.FetchMetrics(metricdatainput)
+	if err != nil {
+		return idle, err
+	}
+
+	sums := make(map[string]float64)
+	for _, mr := range metricdataresults {
+		for _, v := range mr.Values {
+			sums[*mr.Id] += *v
+		}
+	}
+
+	// Activity metrics of the same resource are added up
+	activity := make(map[string]float64)
+	checks := make([]idleCheck, 0)
+	for _, m := range metrics {
+		check := m.Resource.(idleCheck)
+		if _, ok := activity[check.key()]; !ok {
+			checks = append(checks, check)
+		}
+		activity[check.key()] += sums[m.MStat.Id]
+	}
+
+	for _, check := range checks {
+		if activity[check.key()] <= check.threshold {
+			idle = append(idle, check.resource)
+		}
+	}
+	return idle, nil
+}
+
+func GetIdleResources(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]IdleResource, error) {
+	region := *session.Config.Region
+	idle := make([]IdleResource, 0)
+	ec2svc := ec2.New(session)
+
+	idleHours := defaultIdleHours
+	if resource.IdleHours > 0 {
+		idleHours = resource.IdleHours
+	}
+	window := time.Duration(idleHours) * time.Hour
+
+	volumes, err := GetEBSVolumes(session, resource.Tags, resource)
+	if err != nil {
+		return idle, err
+	}
+	for _, v := range volumes {
+		if v.State == "available" {
+			idle = append(idle, IdleResource{
+				ResourceType: "ebs_volume",
+				ResourceId:   v.Id,
+				Reason:       unattachedVolumeReason,
+				Region:       v.Region,
+			})
+		}
+	}
+
+	ips, err := DescribeElasticIps(ec2svc, region)
+	if err != nil {
+		return idle, err
+	}
+	for _, ip := range ips {
+		if !ip.Attached {
+			idle = append(idle, IdleResource{
+				ResourceType: "elastic_ip",
+				ResourceId:   ip.AllocationId,
+				Reason:       unassociatedEipReason,
+				Region:       ip.Region,
+			})
+		}
+	}
+
+	checks := make([]MetricToFetch, 0)
+	natChecks, err := getNatGatewayIdleChecks(session)
+	if err != nil {
+		return idle, err
+	}
+	checks = append(checks, natChecks...)
+
+	elbChecks, err := getLoadBalancerIdleChecks(session, resource)
+	if err != nil {
+		return idle, err
+	}
+	checks = append(checks, elbChecks...)
+
+	cacheChecks, err := getElasticacheIdleChecks(session)
+	if err != nil {
+		return idle, err
+	}
+	checks = append(checks, cacheChecks...)
+
+	idleByMetrics, err := GetIdleByMetrics(cloudwatchSvc, checks, window)
+	if err != nil {
+		return idle, err
+	}
+	return append(idle, idleByMetrics...), nil
+}
+
+func GetWasteDimensions() []string {
+	return []string{
+		"service",
+		"resource_type",
+		"resource_id",
+		"reason",
+		"region",
+		"anodot-collector",
+	}
+}
+
+func GetWasteCustomMetrics() []CustomMetricDefinition {
+	return []CustomMetricDefinition{
+		CustomMetricDefinition{
+			Name:       "idle_resource",
+			Alias:      "IdleResource",
+			TargetType: "sum",
+		},
+	}
+}
+
+func GetIdleResourceMetricProperties(r IdleResource) map[string]string {
+	properties := map[string]string{
+		"service":          "waste",
+		"resource_type":    r.ResourceType,
+		"resource_id":      escape(r.ResourceId),
+		"reason":           r.Reason,
+		"region":           r.Region,
+		"anodot-collector": "aws",
+	}
+
+	for k, v := range properties {
+		if len(v) > 50 || len(v) < 2 {
+			delete(properties, k)
+		}
+	}
+	return properties
+}
+
+func GetWasteMetrics30(session *session.Session, cloudwatchSvc *cloudwatch.CloudWatch, resource *MonitoredResource) ([]metrics3.AnodotMetrics30, error) {
+	metrics := make([]metrics3.AnodotMetrics30, 0)
+
+	idle, err := GetIdleResources(session, cloudwatchSvc, resource)
+	if err != nil {
+		log.Printf("Cloud not check idle resources %v", err)
+		return metrics, err
+	}
+	log.Printf("Found %d idle resources", len(idle))
+
+	for _, cm := range resource.CustomMetrics {
+		if cm == "IdleResource" || cm == "idle_resource" {
+			log.Printf("Processing Waste custom metric IdleResource\n")
+			for _, r := range idle {
+				metrics = append(metrics, metrics3.AnodotMetrics30{
+					Dimensions:   GetIdleResourceMetricProperties(r),
+					Timestamp:    metrics3.AnodotTimestamp{time.Now()},
+					Measurements: map[string]float64{"idle_resource": 1},
+				})
+			}
+		}
+	}
+	return metrics, nil
+}